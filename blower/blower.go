@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math/bits"
 	"os"
 	"path/filepath"
 	"sort"
@@ -13,26 +14,138 @@ import (
 	"time"
 
 	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
 )
 
 var (
-	inputDir    = "./data/"         // Directory containing .awf files
+	inputDir    = "./data/" // Directory containing .awf files
 	outputFile  = "database.awf"
 	outputTxt   = "database.json"
+	configFile  = "config.rcf" // The crawler's config, for its StripParams
 	fileMutex   sync.Mutex
-	seenURLs    = make(map[string]bool) // To remove duplicates
-	uniquePages []PageData              // Store unique entries
+	seenURLs    = make(map[string]int)       // To remove duplicates, index into uniquePages
+	uniquePages []PageData                   // Store unique entries
+	unchanged   = make(map[string]PageData)  // 304 records seen before the page they refer to
+	directives  = make(map[string]directive) // Newest noindex setting of each page
 )
 
+const (
+	nearDupDistance = 3  // SimHash bits apart two pages count as near-duplicates
+	nearDupMinWords = 20 // Shorter pages are stubs that all look alike
+)
+
+// directive is whether the newest record of a page asked to be left out of
+// the index, and when that record was written
+type directive struct {
+	noindex bool
+	crawled time.Time
+}
+
 type PageData struct {
-	URL          string    `json:"url"`            // Page URL
-	Title        string    `json:"title"`          // Page title
-	Description  string    `json:"description"`    // Page description
-	Meta         []Meta    `json:"meta"`           // Page metadata
-	LastModified time.Time `json:"last_modified"`  // Page last modified time
-	Links        []string  `json:"links"`          // Page links
-	Language     string    `json:"language"`       // Page language
-	Favicon      string    `json:"favicon"`        // Page favicon
+	URL          string           `json:"url"`           // Page URL, where any redirects ended
+	Redirects    []Redirect       `json:"redirects"`     // Redirect hops that led to URL, starting at the requested URL
+	Encoding     string           `json:"encoding"`      // Character encoding the page was sent in, such as shift_jis; stored as UTF-8
+	Truncated    bool             `json:"truncated"`     // The page was longer than MaxBodySize and was cut off
+	Title        string           `json:"title"`         // Page title
+	Description  string           `json:"description"`   // Page description
+	Meta         []Meta           `json:"meta"`          // Page metadata
+	LastModified time.Time        `json:"last_modified"` // Page last modified time
+	Links        []Link           `json:"links"`         // Page links
+	Images       []Image          `json:"images"`        // Page images, for image search
+	Canonical    string           `json:"canonical"`     // URL the page declares canonical, "" if none
+	Alternates   []Alternate      `json:"alternates"`    // hreflang translations of the page
+	Language     string           `json:"language"`      // Language declared by <html lang>, "" if none
+	Detected     string           `json:"detected"`      // Language detected from the text, "" if too little text
+	Confidence   float64          `json:"confidence"`    // Confidence in Detected, from 0 to 1
+	Favicon      string           `json:"favicon"`       // Page favicon
+	Content      string           `json:"content"`       // Main text with boilerplate removed, one block per line
+	WordCount    int              `json:"word_count"`    // Words in Content
+	TextRatio    float64          `json:"text_ratio"`    // Length of Content over the length of the HTML
+	ContentHash  string           `json:"content_hash"`  // SHA-256 of the normalized Content
+	SimHash      uint64           `json:"simhash"`       // 64-bit SimHash of Content for near-duplicate detection
+	Structured   StructuredData   `json:"structured"`    // What the page says it is in JSON-LD, OpenGraph and Twitter Card markup
+	Robots       RobotsDirectives `json:"robots"`        // Effective meta robots and X-Robots-Tag directives
+	Validators   Validators       `json:"validators"`    // Cache validators for conditional recrawls
+	Unchanged    bool             `json:"unchanged"`     // 304 Not Modified: keep the previously stored content
+	Crawled      time.Time        `json:"crawled"`       // When the record was written, zero in older crawls
+}
+
+type Image struct {
+	URL         string        `json:"url"`
+	Sources     []ImageSource `json:"sources"`
+	Alt         string        `json:"alt"`
+	Title       string        `json:"title"`
+	Width       int           `json:"width"`
+	Height      int           `json:"height"`
+	Caption     string        `json:"caption"`
+	ContentType string        `json:"content_type"`
+	Size        int64         `json:"size"`
+}
+
+type ImageSource struct {
+	URL        string `json:"url"`
+	Descriptor string `json:"descriptor"`
+	Media      string `json:"media"`
+	Type       string `json:"type"`
+}
+
+type StructuredData struct {
+	Type        string       `json:"type"`
+	Author      string       `json:"author"`
+	Published   time.Time    `json:"published"`
+	Image       string       `json:"image"`
+	Breadcrumbs []Breadcrumb `json:"breadcrumbs"`
+}
+
+type Breadcrumb struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type RobotsDirectives struct {
+	NoIndex   bool `json:"noindex"`
+	NoFollow  bool `json:"nofollow"`
+	NoArchive bool `json:"noarchive"`
+	NoSnippet bool `json:"nosnippet"`
+}
+
+type Validators struct {
+	ETag         string `json:"etag"`
+	LastModified string `json:"last_modified"`
+}
+
+type Redirect struct {
+	URL    string `json:"url"`
+	Status int    `json:"status"`
+}
+
+type Alternate struct {
+	Lang string `json:"lang"`
+	URL  string `json:"url"`
+}
+
+// Link is an outgoing link from a page
+type Link struct {
+	URL      string   `json:"url"`      // Canonical target URL
+	Text     string   `json:"text"`     // Anchor text, or the alt text of a linked image
+	Rel      []string `json:"rel"`      // rel values such as nofollow, ugc and sponsored
+	Internal bool     `json:"internal"` // Target is on the same host as the page
+}
+
+// DecodeMsgpack also accepts the bare URL strings older crawls stored
+func (l *Link) DecodeMsgpack(dec *msgpack.Decoder) error {
+	code, err := dec.PeekCode()
+	if err != nil {
+		return err
+	}
+	if msgpcode.IsString(code) {
+		*l = Link{}
+		l.URL, err = dec.DecodeString()
+		return err
+	}
+
+	type plain Link // Drops this method so decoding does not recurse
+	return dec.Decode((*plain)(l))
 }
 
 type Meta struct {
@@ -81,8 +194,27 @@ func readAWFFile(filename string) error {
 			continue
 		}
 
-		if _, exists := seenURLs[page.URL]; !exists {
-			seenURLs[page.URL] = true
+		// Older crawls stored raw URLs, so dedup on the canonical form
+		key := Canonicalize(page.URL)
+		if key == "" {
+			key = page.URL
+		}
+		if !page.Unchanged {
+			noteDirective(key, page)
+		}
+		if page.Robots.NoIndex {
+			continue
+		}
+		if page.Unchanged {
+			mergeUnchanged(key, page)
+			continue
+		}
+		if _, exists := seenURLs[key]; !exists {
+			seenURLs[key] = len(uniquePages)
+			if update, ok := unchanged[key]; ok {
+				applyUnchanged(&page, update)
+				delete(unchanged, key)
+			}
 			uniquePages = append(uniquePages, page)
 		}
 	}
@@ -90,6 +222,156 @@ func readAWFFile(filename string) error {
 	return nil
 }
 
+// mergeUnchanged applies a 304 record to the page it refers to, or holds it
+// until that page turns up in a later file
+func mergeUnchanged(key string, update PageData) {
+	if index, exists := seenURLs[key]; exists {
+		applyUnchanged(&uniquePages[index], update)
+		return
+	}
+	if held, ok := unchanged[key]; !ok || update.LastModified.After(held.LastModified) {
+		unchanged[key] = update
+	}
+}
+
+// applyUnchanged keeps the stored content and refreshes its validators
+func applyUnchanged(page *PageData, update PageData) {
+	if update.Validators.ETag != "" {
+		page.Validators.ETag = update.Validators.ETag
+	}
+	if update.Validators.LastModified != "" {
+		page.Validators.LastModified = update.Validators.LastModified
+	}
+	if update.LastModified.After(page.LastModified) {
+		page.LastModified = update.LastModified
+	}
+}
+
+// noteDirective keeps the noindex setting of a page's newest record. A 304
+// carries no directives, so it is not passed in. Older crawls have no time
+// on their records; between records of the same time noindex wins
+func noteDirective(key string, page PageData) {
+	held, ok := directives[key]
+	if ok && (page.Crawled.Before(held.crawled) || page.Crawled.Equal(held.crawled) && held.noindex) {
+		return
+	}
+	directives[key] = directive{noindex: page.Robots.NoIndex, crawled: page.Crawled}
+}
+
+// dropNoindex removes pages whose newest record asks not to be indexed,
+// including copies from crawls made before it did
+func dropNoindex() {
+	kept := uniquePages[:0]
+	for _, page := range uniquePages {
+		key := Canonicalize(page.URL)
+		if key == "" {
+			key = page.URL
+		}
+		if !directives[key].noindex {
+			kept = append(kept, page)
+		}
+	}
+	if dropped := len(uniquePages) - len(kept); dropped > 0 {
+		fmt.Printf("Dropped %d pages now marked noindex\n", dropped)
+	}
+	uniquePages = kept
+}
+
+// collapseCanonicals keeps one document per declared canonical URL,
+// preferring the record fetched from the canonical URL itself
+func collapseCanonicals() {
+	byCanonical := make(map[string]int) // Canonical URL -> index in kept
+	kept := make([]PageData, 0, len(uniquePages))
+
+	for _, page := range uniquePages {
+		url := Canonicalize(page.URL)
+		if url == "" {
+			url = page.URL
+		}
+		canonical := url
+		if page.Canonical != "" {
+			canonical = page.Canonical
+		}
+
+		index, exists := byCanonical[canonical]
+		if !exists {
+			byCanonical[canonical] = len(kept)
+			kept = append(kept, page)
+			continue
+		}
+		if url == canonical {
+			kept[index] = page
+		}
+	}
+
+	if collapsed := len(uniquePages) - len(kept); collapsed > 0 {
+		fmt.Printf("Collapsed %d duplicate pages into their canonical URL\n", collapsed)
+	}
+	uniquePages = kept
+}
+
+// collapseNearDuplicates groups pages whose text is the same or nearly the
+// same, as SimHash tells, and keeps the page with the shortest URL from
+// each group: mirrors, print views and session-id URLs tend to be longer
+func collapseNearDuplicates() {
+	parent := make([]int, len(uniquePages))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	// Pages within the distance share at least one of four 16-bit bands
+	byHash := make(map[string]int)
+	byBand := make(map[uint64][]int)
+	for i, page := range uniquePages {
+		if page.ContentHash == "" || page.WordCount < nearDupMinWords {
+			continue
+		}
+		if j, ok := byHash[page.ContentHash]; ok {
+			parent[find(i)] = find(j)
+		} else {
+			byHash[page.ContentHash] = i
+		}
+		for band := 0; band < 4; band++ {
+			key := uint64(band)<<16 | (page.SimHash>>(band*16))&0xffff
+			for _, j := range byBand[key] {
+				if bits.OnesCount64(page.SimHash^uniquePages[j].SimHash) <= nearDupDistance {
+					parent[find(i)] = find(j)
+				}
+			}
+			byBand[key] = append(byBand[key], i)
+		}
+	}
+
+	representative := make(map[int]int) // Cluster root -> index of the page kept
+	for i, page := range uniquePages {
+		root := find(i)
+		best, ok := representative[root]
+		if !ok || len(page.URL) < len(uniquePages[best].URL) ||
+			(len(page.URL) == len(uniquePages[best].URL) && page.URL < uniquePages[best].URL) {
+			representative[root] = i
+		}
+	}
+
+	kept := make([]PageData, 0, len(representative))
+	for i, page := range uniquePages {
+		if representative[find(i)] == i {
+			kept = append(kept, page)
+		}
+	}
+
+	if collapsed := len(uniquePages) - len(kept); collapsed > 0 {
+		fmt.Printf("Collapsed %d near-duplicate pages\n", collapsed)
+	}
+	uniquePages = kept
+}
+
 // Write unique data back to AWF format
 func writeCombinedAWF() error {
 	file, err := os.Create(outputFile)
@@ -167,6 +449,10 @@ func main() {
 		return
 	}
 
+	if !loadStripParams(configFile) {
+		fmt.Println("No", configFile, "found; only the built-in tracking parameters are stripped from URLs")
+	}
+
 	for _, file := range files {
 		err := readAWFFile(file)
 		if err != nil {
@@ -175,6 +461,15 @@ func main() {
 		}
 	}
 
+	dropNoindex()
+	collapseCanonicals()
+	collapseNearDuplicates()
+
+	// A 304 without the full page it refers to has no content to keep
+	if len(unchanged) > 0 {
+		fmt.Printf("Dropped %d unchanged records with no earlier copy\n", len(unchanged))
+	}
+
 	if err := writeCombinedAWF(); err != nil {
 		fmt.Println("Error saving combined AWF:", err)
 	}
@@ -183,4 +478,3 @@ func main() {
 		fmt.Println("Error saving links:", err)
 	}
 }
-
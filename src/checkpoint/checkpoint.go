@@ -0,0 +1,73 @@
+package checkpoint
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	"webcrawler/frontier"
+)
+
+const stateFile = "frontier.ckpt"
+
+// State is a snapshot of the crawl frontier. URLs beyond the scheduler's
+// memory stay in the frontier's segment files, and the seen set and hints
+// in their own files; the checkpoint only records how far into each to
+// resume
+type State struct {
+	Pending   []frontier.Item `msgpack:"pending"`   // URLs queued in memory or in flight
+	Spilled   frontier.State  `msgpack:"spilled"`   // Segment files holding the other URLs not crawled yet
+	Seen      int64           `msgpack:"seen"`      // Hashes in the seen set's file
+	Hints     int64           `msgpack:"hints"`     // Bytes of the file of sitemap hints and publish dates
+	Processed int64           `msgpack:"processed"` // Pages crawled so far
+	Saved     int64           `msgpack:"saved"`     // Length of the storage file; records past it were saved later
+	SavedAt   time.Time       `msgpack:"saved_at"`  // When the snapshot was taken
+}
+
+// Save writes the state to dir, replacing any previous checkpoint atomically
+func Save(dir string, state *State) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := msgpack.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temp file first so a crash never leaves a torn checkpoint
+	tmp := filepath.Join(dir, stateFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, stateFile))
+}
+
+// Load reads the checkpoint from dir. Returns nil if there is none
+func Load(dir string) (*State, error) {
+	data, err := os.ReadFile(filepath.Join(dir, stateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var state State
+	if err := msgpack.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// Clear removes the checkpoint once a crawl has finished
+func Clear(dir string) error {
+	err := os.Remove(filepath.Join(dir, stateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
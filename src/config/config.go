@@ -1,40 +1,164 @@
 package config
 
+import "time"
+
 type Config struct {
-	WorkerCount int    // Number of worker goroutines
-	RateLimit   int    // Number of requests per second
-	QueueSize   int    // Maximum number of URLs in the queue
-	UserAgent   string // User agent string
-	MaxDepth    int    // Maximum depth for crawling
+	WorkerCount        int           // Number of worker goroutines
+	RateLimit          int           // Number of requests per second to a single host
+	HostMaxInFlight    int           // Maximum concurrent requests to a single host
+	MaxCrawlDelay      time.Duration // Upper bound on a robots.txt Crawl-delay we honor
+	MaxIdleConns       int           // Keep-alive connections kept open across all hosts
+	IdleConnsPerHost   int           // Keep-alive connections kept open to a single host
+	DialTimeout        time.Duration // Time to open a TCP connection
+	TLSTimeout         time.Duration // Time for the TLS handshake
+	HeaderTimeout      time.Duration // Time from sending a request to its response headers
+	BodyTimeout        time.Duration // Time to read a response body
+	MaxBodySize        int64         // Bytes of a response read; longer pages are truncated
+	MaxRetries         int           // Retries for timeouts, dropped connections, 429 and 5xx
+	RetryBaseDelay     time.Duration // Backoff before the first retry, doubled each time
+	RetryMaxDelay      time.Duration // Longest backoff; a longer Retry-After gives up
+	QueueSize          int           // URLs queued in memory; the rest wait in segment files on disk
+	SegmentSize        int           // URLs per frontier segment file
+	SeenSize           int           // URLs the seen set's filter is sized for; past that more new URLs are taken for seen
+	UserAgent          string        // User agent string
+	MaxDepth           int           // Maximum depth for crawling
+	Scope              string        // Scope of seeds that set none: "host", "domain" or "any"
+	MaxOffsiteHops     int           // Links in a row followed outside a seed's scope
+	MaxRedirects       int           // Redirect hops followed before giving up
+	NearDupDistance    int           // SimHash bits apart two pages count as near-duplicates, -1 disables
+	SitemapLimit       int           // Maximum URLs taken from each seed's sitemaps, 0 disables them
+	ImageHeaders       int           // HEAD requests per page to record image type and size, 0 disables them
+	StateDir           string        // Directory for crawl checkpoints
+	CheckpointInterval time.Duration // How often the frontier is saved to disk
+	RecrawlDatabase    string        // AWF database to import recrawl validators from, "" for none
+	Continuous         bool          // Keep running and revisit pages as they come due
+	RevisitInitial     time.Duration // Revisit interval for a page with no change history
+	RevisitMin         time.Duration // Shortest revisit interval
+	RevisitMax         time.Duration // Longest revisit interval
+}
+
+// Scope limits how far a crawl may wander from its seed. A URL is on-site
+// if it is on the seed's host or registrable domain, as Mode says, or on
+// one of the allowed domains; "any" puts the whole web on-site
+type Scope struct {
+	Mode           string   // "host", "domain" or "any"; "" for the default
+	Allow          []string // Further domains, subdomains included, that are on-site
+	MaxOffsiteHops int      // Links in a row followed off-site; -1 for the default
+}
+
+// HostLimit overrides the per-host politeness defaults for one domain
+type HostLimit struct {
+	RateLimit   float64 // Requests per second
+	MaxInFlight int     // Maximum concurrent requests
 }
 
 func DefaultConfig() *Config {
 	return &Config{
-		WorkerCount: 10,
-		RateLimit:   5,
-		QueueSize:   100000,
-		UserAgent:   "AmberRake",
-		MaxDepth:    5,
+		WorkerCount:        10,
+		RateLimit:          5,
+		HostMaxInFlight:    2,
+		MaxCrawlDelay:      time.Minute,
+		MaxIdleConns:       100,
+		IdleConnsPerHost:   2,
+		DialTimeout:        5 * time.Second,
+		TLSTimeout:         5 * time.Second,
+		HeaderTimeout:      10 * time.Second,
+		BodyTimeout:        30 * time.Second,
+		MaxBodySize:        10 << 20,
+		MaxRetries:         3,
+		RetryBaseDelay:     2 * time.Second,
+		RetryMaxDelay:      2 * time.Minute,
+		QueueSize:          100000,
+		SegmentSize:        10000,
+		SeenSize:           10000000,
+		UserAgent:          "AmberRake",
+		MaxDepth:           5,
+		Scope:              "any",
+		MaxOffsiteHops:     0,
+		MaxRedirects:       10,
+		NearDupDistance:    3,
+		SitemapLimit:       50000,
+		ImageHeaders:       0,
+		StateDir:           "state",
+		CheckpointInterval: 30 * time.Second,
+		RecrawlDatabase:    "",
+		Continuous:         false,
+		RevisitInitial:     24 * time.Hour,
+		RevisitMin:         time.Hour,
+		RevisitMax:         30 * 24 * time.Hour,
 	}
 }
 
 func LowResourceConfig() *Config {
 	return &Config{
-		WorkerCount: 2,
-		RateLimit:   1,
-		QueueSize:   1000,
-		UserAgent:   "AmberRake",
-		MaxDepth:    2,
+		WorkerCount:        2,
+		RateLimit:          1,
+		HostMaxInFlight:    1,
+		MaxCrawlDelay:      time.Minute,
+		MaxIdleConns:       10,
+		IdleConnsPerHost:   1,
+		DialTimeout:        10 * time.Second,
+		TLSTimeout:         10 * time.Second,
+		HeaderTimeout:      15 * time.Second,
+		BodyTimeout:        30 * time.Second,
+		MaxBodySize:        2 << 20,
+		MaxRetries:         2,
+		RetryBaseDelay:     5 * time.Second,
+		RetryMaxDelay:      time.Minute,
+		QueueSize:          1000,
+		SegmentSize:        1000,
+		SeenSize:           1000000,
+		UserAgent:          "AmberRake",
+		MaxDepth:           2,
+		Scope:              "any",
+		MaxOffsiteHops:     0,
+		MaxRedirects:       5,
+		NearDupDistance:    3,
+		SitemapLimit:       1000,
+		ImageHeaders:       0,
+		StateDir:           "state",
+		CheckpointInterval: time.Minute,
+		RecrawlDatabase:    "",
+		Continuous:         false,
+		RevisitInitial:     24 * time.Hour,
+		RevisitMin:         time.Hour,
+		RevisitMax:         30 * 24 * time.Hour,
 	}
 }
 
 func ProductionConfig() *Config {
 	return &Config{
-		WorkerCount: 20,
-		RateLimit:   10,
-		QueueSize:   1000000,
-		UserAgent:   "AmberRake",
-		MaxDepth:    10,
+		WorkerCount:        20,
+		RateLimit:          10,
+		HostMaxInFlight:    4,
+		MaxCrawlDelay:      time.Minute,
+		MaxIdleConns:       1000,
+		IdleConnsPerHost:   4,
+		DialTimeout:        5 * time.Second,
+		TLSTimeout:         5 * time.Second,
+		HeaderTimeout:      10 * time.Second,
+		BodyTimeout:        time.Minute,
+		MaxBodySize:        10 << 20,
+		MaxRetries:         5,
+		RetryBaseDelay:     time.Second,
+		RetryMaxDelay:      5 * time.Minute,
+		QueueSize:          1000000,
+		SegmentSize:        100000,
+		SeenSize:           100000000,
+		UserAgent:          "AmberRake",
+		MaxDepth:           10,
+		Scope:              "any",
+		MaxOffsiteHops:     0,
+		MaxRedirects:       10,
+		NearDupDistance:    3,
+		SitemapLimit:       1000000,
+		ImageHeaders:       0,
+		StateDir:           "state",
+		CheckpointInterval: 15 * time.Second,
+		RecrawlDatabase:    "",
+		Continuous:         false,
+		RevisitInitial:     24 * time.Hour,
+		RevisitMin:         time.Hour,
+		RevisitMax:         30 * 24 * time.Hour,
 	}
 }
-
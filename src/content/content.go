@@ -0,0 +1,319 @@
+package content
+
+import (
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// Result is the main text of a page and how much of the page it makes up
+type Result struct {
+	Text      string  // Main content, one block per line
+	WordCount int     // Words in Text
+	TextRatio float64 // Length of Text over the length of the HTML
+}
+
+const (
+	minParagraphLen = 25  // Shorter blocks are menus and labels, not prose
+	minTopScore     = 20  // Below this no candidate is convincing, use the whole body
+	minFormText     = 200 // Forms with less text are search boxes, logins and sign-ups
+	minFormCommas   = 10  // Forms with more commas than this read like prose, as in Readability
+)
+
+var (
+	// Elements that never hold the main content
+	junkSelector = "script, style, noscript, template, iframe, svg, canvas, button, select, nav, aside, [hidden], [aria-hidden='true']"
+
+	// Class and id hints, as in Readability
+	negativeHint = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|cookie|disqus|extra|foot|header|menu|modal|nav|popup|promo|related|remark|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|tags|widget|\bad-|\bads\b`)
+	positiveHint = regexp.MustCompile(`(?i)article|body|content|entry|h-entry|main|page|post|story|text`)
+
+	blockTags = map[string]bool{
+		"p": true, "pre": true, "blockquote": true, "li": true, "td": true, "dd": true,
+		"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	}
+)
+
+// Extract finds the main content of a page with a Readability-style text
+// density heuristic. The document is not modified
+func Extract(doc *goquery.Document) Result {
+	htmlLen := 0
+	if raw, err := doc.Html(); err == nil {
+		htmlLen = len(raw)
+	}
+
+	body := doc.Find("body").First().Clone()
+	clean(body)
+
+	var text string
+	if top := topCandidate(body); top != nil {
+		text = blockText(top)
+	}
+	if text == "" {
+		text = blockText(body)
+	}
+
+	result := Result{
+		Text:      text,
+		WordCount: len(strings.Fields(text)),
+	}
+	if htmlLen > 0 {
+		result.TextRatio = math.Round(float64(len(text))/float64(htmlLen)*1000) / 1000
+	}
+	return result
+}
+
+// clean drops elements that are boilerplate by tag or by their class and id
+func clean(body *goquery.Selection) {
+	// Fields are counted before select boxes go with the junk
+	fields := make(map[*html.Node]int)
+	body.Find("form").Each(func(i int, form *goquery.Selection) {
+		fields[form.Nodes[0]] = textFields(form)
+	})
+	body.Find(junkSelector).Remove()
+
+	// Site headers and footers go, but an article's own header keeps its title
+	body.Find("header, footer").Each(func(i int, s *goquery.Selection) {
+		if s.ParentsFiltered("article, main").Length() == 0 {
+			s.Remove()
+		}
+	})
+	body.Find("[class], [id]").Each(func(i int, s *goquery.Selection) {
+		hints := s.AttrOr("class", "") + " " + s.AttrOr("id", "")
+		if negativeHint.MatchString(hints) && !positiveHint.MatchString(hints) && s.Is("div, section, ul, ol, span, p, table") {
+			s.Remove()
+		}
+	})
+
+	// ASP.NET WebForms wraps the whole page in one <form>, so forms are
+	// judged by their contents, as Readability does, and the one holding
+	// the main content always stays
+	var top []*html.Node
+	if candidate := topCandidate(body); candidate != nil {
+		top = candidate.Nodes
+	}
+	forms := body.Find("form")
+	for i := forms.Length() - 1; i >= 0; i-- {
+		form := forms.Eq(i)
+		if !containsAny(form.Nodes[0], top) && isBoilerplateForm(form, fields[form.Nodes[0]]) {
+			form.Remove()
+		}
+	}
+}
+
+// textFields counts the fields of a form that take typed text. Hidden
+// inputs and buttons say nothing about what the form is for
+func textFields(form *goquery.Selection) int {
+	count := form.Find("textarea, select").Length()
+	form.Find("input").Each(func(i int, input *goquery.Selection) {
+		switch strings.ToLower(input.AttrOr("type", "text")) {
+		case "text", "email", "password", "search", "tel", "url", "number":
+			count++
+		}
+	})
+	return count
+}
+
+// containsAny reports whether any of nodes is node or sits inside it
+func containsAny(node *html.Node, nodes []*html.Node) bool {
+	for _, n := range nodes {
+		for ; n != nil; n = n.Parent {
+			if n == node {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isBoilerplateForm reports whether a form is little more than its fields:
+// hinted as boilerplate, short, mostly links, or with more text fields than
+// paragraphs to go with them. A form with many commas is prose and stays
+func isBoilerplateForm(form *goquery.Selection, fields int) bool {
+	if classWeight(form.Nodes[0]) < 0 {
+		return true
+	}
+	text := strings.TrimSpace(form.Text())
+	if strings.Count(text, ",") >= minFormCommas {
+		return false
+	}
+	if len(text) < minFormText || linkDensity(form) > 0.5 {
+		return true
+	}
+
+	paragraphs := 0
+	form.Find("p, pre, td, blockquote").Each(func(i int, s *goquery.Selection) {
+		if len(strings.TrimSpace(s.Text())) >= minParagraphLen {
+			paragraphs++
+		}
+	})
+	return fields > 0 && fields*3 > paragraphs
+}
+
+// topCandidate scores the parents of every paragraph and returns the best
+// container together with its siblings that look like part of the article
+func topCandidate(body *goquery.Selection) *goquery.Selection {
+	scores := make(map[*html.Node]float64)
+	var order []*html.Node
+
+	addScore := func(node *html.Node, score float64) {
+		if node == nil || node.Type != html.ElementNode {
+			return
+		}
+		if _, seen := scores[node]; !seen {
+			scores[node] = baseScore(node)
+			order = append(order, node)
+		}
+		scores[node] += score
+	}
+
+	body.Find("p, pre, td, blockquote").Each(func(i int, s *goquery.Selection) {
+		text := strings.TrimSpace(s.Text())
+		if len(text) < minParagraphLen {
+			return
+		}
+
+		// One point, one per comma, one per 100 characters up to three
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text))/100, 3)
+		parent := s.Nodes[0].Parent
+		addScore(parent, score)
+		if parent != nil {
+			addScore(parent.Parent, score/2)
+		}
+	})
+
+	var top *html.Node
+	topScore := 0.0
+	for _, node := range order {
+		score := scores[node] * (1 - linkDensity(goquery.NewDocumentFromNode(node).Selection))
+		scores[node] = score
+		if score > topScore {
+			top, topScore = node, score
+		}
+	}
+	if top == nil || topScore < minTopScore {
+		return nil
+	}
+
+	// Paragraphs sitting right in <body>
+	if top.Parent == nil {
+		return body
+	}
+
+	// Pull in siblings that scored well or read like prose
+	threshold := math.Max(10, topScore*0.2)
+	var nodes []*html.Node
+	for sibling := top.Parent.FirstChild; sibling != nil; sibling = sibling.NextSibling {
+		if sibling.Type != html.ElementNode {
+			continue
+		}
+		if sibling == top || scores[sibling] >= threshold {
+			nodes = append(nodes, sibling)
+			continue
+		}
+		if sibling.Data == "p" {
+			s := goquery.NewDocumentFromNode(sibling).Selection
+			text := strings.TrimSpace(s.Text())
+			if len(text) > 80 && linkDensity(s) < 0.25 {
+				nodes = append(nodes, sibling)
+			}
+		}
+	}
+	return body.FindNodes(nodes...)
+}
+
+// baseScore weighs a container by its tag and its class and id hints
+func baseScore(node *html.Node) float64 {
+	score := 0.0
+	switch node.Data {
+	case "article", "main":
+		score += 10
+	case "div":
+		score += 5
+	case "pre", "td", "blockquote":
+		score += 3
+	case "ol", "ul", "dl", "dd", "dt", "li", "form":
+		score -= 3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score -= 5
+	}
+	return score + classWeight(node)
+}
+
+// classWeight scores a node's class and id hints, as in Readability
+func classWeight(node *html.Node) float64 {
+	weight := 0.0
+	for _, attr := range node.Attr {
+		if attr.Key != "class" && attr.Key != "id" {
+			continue
+		}
+		if negativeHint.MatchString(attr.Val) {
+			weight -= 25
+		}
+		if positiveHint.MatchString(attr.Val) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// linkDensity is the share of a selection's text that sits inside links
+func linkDensity(s *goquery.Selection) float64 {
+	textLen := len(strings.TrimSpace(s.Text()))
+	if textLen == 0 {
+		return 0
+	}
+	linkLen := 0
+	s.Find("a").Each(func(i int, a *goquery.Selection) {
+		linkLen += len(strings.TrimSpace(a.Text()))
+	})
+	return float64(linkLen) / float64(textLen)
+}
+
+// blockText renders a selection as plain text, one block element per line
+func blockText(s *goquery.Selection) string {
+	var lines []string
+	var current strings.Builder
+
+	flush := func() {
+		if line := strings.Join(strings.Fields(current.String()), " "); line != "" {
+			lines = append(lines, line)
+		}
+		current.Reset()
+	}
+
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		switch node.Type {
+		case html.TextNode:
+			current.WriteString(node.Data)
+			current.WriteByte(' ')
+			return
+		case html.ElementNode:
+			if node.Data == "br" {
+				flush()
+				return
+			}
+		}
+
+		block := node.Type == html.ElementNode && (blockTags[node.Data] || node.Data == "div" || node.Data == "section" || node.Data == "article")
+		if block {
+			flush()
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+		if block {
+			flush()
+		}
+	}
+
+	for _, node := range s.Nodes {
+		walk(node)
+		flush()
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,56 @@
+package content
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const paragraph = "The committee met on Tuesday to review the budget for the coming year and agreed to fund the new library wing in full. "
+
+func TestExtractForms(t *testing.T) {
+	article := "<div class=\"article\">" + strings.Repeat("<p>"+strings.Repeat(paragraph, 3)+"</p>", 4) + "</div>"
+	tests := []struct {
+		name     string
+		html     string
+		want     string // Text the result must contain
+		dropped  string // Text the result must not contain
+		minWords int
+	}{
+		{
+			name: "webforms page keeps its content",
+			html: `<html><body><form id="form1" method="post" action="./Default.aspx">
+				<input type="hidden" name="__VIEWSTATE" value="abc">
+				<div class="search"><input type="text" name="q"><input type="submit" value="Search"></div>
+				` + article + `
+			</form></body></html>`,
+			want:     "committee met on Tuesday",
+			minWords: 200,
+		},
+		{
+			name: "login form beside the article goes",
+			html: `<html><body>` + article + `
+				<form action="/login"><p>Sign in to comment on this story and follow the authors you like best.</p>
+				<input type="email" name="user"><input type="password" name="pass"><textarea name="note"></textarea>
+				<input type="submit" value="Sign in"></form>
+			</body></html>`,
+			want:     "committee met on Tuesday",
+			dropped:  "Sign in to comment",
+			minWords: 200,
+		},
+	}
+	for _, test := range tests {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(test.html))
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := Extract(doc)
+		if !strings.Contains(result.Text, test.want) || result.WordCount < test.minWords {
+			t.Errorf("%s: got %d words, text %q", test.name, result.WordCount, result.Text)
+		}
+		if test.dropped != "" && strings.Contains(result.Text, test.dropped) {
+			t.Errorf("%s: kept %q", test.name, test.dropped)
+		}
+	}
+}
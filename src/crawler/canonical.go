@@ -0,0 +1,108 @@
+package crawler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"webcrawler/types"
+	"webcrawler/utils"
+)
+
+// headerLink is one entry of an HTTP Link header
+type headerLink struct {
+	url    string
+	params map[string]string // rel, hreflang, ... lowercased
+}
+
+// canonicalURLs finds the page's declared canonical URL and its hreflang
+// alternates, from <link> tags first and the Link header otherwise. URLs
+// come back in canonical form; the canonical is "" if none is declared
+func canonicalURLs(pageURL string, header http.Header, doc *goquery.Document) (string, []types.Alternate) {
+	canonical := ""
+	var alternates []types.Alternate
+
+	doc.Find("link[rel][href]").Each(func(i int, s *goquery.Selection) {
+		href := resolveWebURL(pageURL, s.AttrOr("href", ""))
+		if href == "" {
+			return
+		}
+		rel := s.AttrOr("rel", "")
+		if hasRel(rel, "canonical") && canonical == "" {
+			canonical = href
+		}
+		if lang := s.AttrOr("hreflang", ""); hasRel(rel, "alternate") && lang != "" {
+			alternates = append(alternates, types.Alternate{Lang: strings.ToLower(lang), URL: href})
+		}
+	})
+
+	for _, link := range parseLinkHeader(header.Values("Link")) {
+		href := resolveWebURL(pageURL, link.url)
+		if href == "" {
+			continue
+		}
+		rel := link.params["rel"]
+		if hasRel(rel, "canonical") && canonical == "" {
+			canonical = href
+		}
+		if lang := link.params["hreflang"]; hasRel(rel, "alternate") && lang != "" && !hasAlternate(alternates, lang) {
+			alternates = append(alternates, types.Alternate{Lang: strings.ToLower(lang), URL: href})
+		}
+	}
+	return canonical, alternates
+}
+
+func hasAlternate(alternates []types.Alternate, lang string) bool {
+	for _, alternate := range alternates {
+		if alternate.Lang == strings.ToLower(lang) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveWebURL resolves and canonicalizes an http(s) link, or returns ""
+func resolveWebURL(baseURL, link string) string {
+	target := utils.Canonicalize(utils.ResolveURL(baseURL, strings.TrimSpace(link)))
+	if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+		return ""
+	}
+	return target
+}
+
+// parseLinkHeader splits Link header values (RFC 8288) into their entries
+func parseLinkHeader(values []string) []headerLink {
+	var links []headerLink
+	for _, value := range values {
+		for value != "" {
+			start := strings.Index(value, "<")
+			end := strings.Index(value, ">")
+			if start < 0 || end < start {
+				break
+			}
+			link := headerLink{url: value[start+1 : end], params: make(map[string]string)}
+			value = value[end+1:]
+
+			// Parameters run until the comma that starts the next link
+			next := strings.Index(value, "<")
+			params := value
+			if next >= 0 {
+				params, value = value[:next], value[next:]
+			} else {
+				value = ""
+			}
+			for _, param := range strings.Split(params, ";") {
+				key, val, found := strings.Cut(param, "=")
+				if !found {
+					continue
+				}
+				key = strings.ToLower(strings.TrimSpace(key))
+				val = strings.Trim(strings.TrimSpace(val), `",`)
+				link.params[key] = val
+			}
+			links = append(links, link)
+		}
+	}
+	return links
+}
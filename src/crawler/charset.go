@@ -0,0 +1,29 @@
+package crawler
+
+import (
+	"bytes"
+	"io"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/transform"
+)
+
+// decodeBody transcodes a page to UTF-8 for parsing, returning the name of
+// the encoding it was in. A byte order mark wins, then the Content-Type
+// charset, then <meta charset> or http-equiv in the first 1024 bytes.
+// Undeclared pages are UTF-8 if they decode as such, else windows-1252 as
+// browsers assume
+func decodeBody(body []byte, contentType string) (io.Reader, string) {
+	encoding, name, certain := charset.DetermineEncoding(body, contentType)
+
+	// Only the first 1024 bytes were looked at; an ASCII head says nothing
+	if !certain && name == "windows-1252" && utf8.Valid(body) {
+		return bytes.NewReader(body), "utf-8"
+	}
+	if name == "utf-8" {
+		// Drop a BOM so it does not end up in the first text node
+		return bytes.NewReader(bytes.TrimPrefix(body, []byte("\xef\xbb\xbf"))), name
+	}
+	return transform.NewReader(bytes.NewReader(body), encoding.NewDecoder()), name
+}
@@ -0,0 +1,152 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"webcrawler/checkpoint"
+	"webcrawler/frontier"
+	"webcrawler/revisit"
+	"webcrawler/storage"
+	"webcrawler/utils"
+)
+
+// Run crawls continuously: every page crawled is revisited when its
+// schedule says it is due, until the context is canceled
+func (c *Crawler) Run(ctx context.Context, urls []string) {
+	c.revisit = revisit.New(c.config)
+	if err := c.revisit.Load(); err != nil {
+		fmt.Println("\r[Revisit Error]", err)
+	}
+	c.loadValidators()
+
+	c.startWorkers(ctx)
+
+	// Continue the frontier the last run left, if any. A fresh schedule
+	// starts from the seeds; after that it drives itself
+	restored := c.restore()
+	fresh := !restored && c.revisit.Len() == 0
+	if !restored {
+		c.openState(&checkpoint.State{})
+	}
+	if fresh {
+		for _, url := range urls {
+			c.addToQueue(url, nil, 0)
+		}
+	}
+	if restored || fresh {
+		for _, url := range urls {
+			c.wg.Add(1)
+			go c.ingestSitemaps(ctx, url)
+		}
+	}
+	fmt.Printf("Continuous mode: %d URLs scheduled\n", c.revisit.Len())
+
+	// Save the frontier and the schedule if a signal stops the crawler
+	storage.OnShutdown(c.saveCheckpoint)
+	storage.OnShutdown(c.saveSchedule)
+
+	ticker := time.NewTicker(c.config.CheckpointInterval)
+	defer ticker.Stop()
+	due := time.NewTimer(0)
+	defer due.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println("\nCrawling stopped.")
+			c.saveCheckpoint()
+			c.saveSchedule()
+			c.queue.Close()
+			c.closeState()
+			return
+		case <-ticker.C:
+			c.saveCheckpoint()
+			c.saveSchedule()
+		case <-due.C:
+			for _, entry := range c.revisit.Due(time.Now()) {
+				c.queueRevisit(entry)
+			}
+			due.Reset(c.untilDue())
+		}
+	}
+}
+
+// untilDue returns how long to wait for the next revisit. A page recorded
+// in the meantime is not due for at least RevisitMin, so the wait is never
+// longer than that
+func (c *Crawler) untilDue() time.Duration {
+	wait := c.config.RevisitMin
+	if next, ok := c.revisit.NextDue(); ok {
+		wait = min(wait, time.Until(next))
+	}
+	return max(wait, time.Second)
+}
+
+// queueRevisit queues a URL that has been crawled before. It skips the seen
+// check addToQueue does; the schedule does not hand out a URL again until
+// its last visit is recorded or released
+func (c *Crawler) queueRevisit(entry revisit.Entry) {
+	targetURL := entry.URL
+	if allowed, _ := utils.CheckURL(targetURL); !allowed {
+		c.revisit.Release(targetURL, time.Now())
+		return
+	}
+
+	// Entries saved before origins were kept count as their own seed
+	origin := entry.Origin
+	if origin.Seed == "" {
+		origin.Seed = targetURL
+	}
+
+	c.visitedMu.Lock()
+	c.seen.Add(targetURL)
+	c.wg.Add(1)
+	c.queue.Push(frontier.Item{URL: targetURL, Depth: entry.Depth, Origin: origin})
+	c.visitedMu.Unlock()
+	fmt.Println("\r[Revisit]", targetURL)
+}
+
+// recordVisit feeds a visit into the revisit schedule in continuous mode
+func (c *Crawler) recordVisit(visit revisit.Visit) {
+	if c.revisit == nil {
+		return
+	}
+
+	c.visitedMu.Lock()
+	entry, _ := c.hints.Sitemap(visit.URL)
+	visit.ChangeFreq = entry.ChangeFreq
+	c.visitedMu.Unlock()
+
+	c.revisit.Record(visit, time.Now())
+}
+
+// saveSchedule persists the revisit schedule and recrawl validators
+func (c *Crawler) saveSchedule() {
+	if err := storage.Flush(); err != nil {
+		fmt.Println("\r[Revisit Error]", err)
+	}
+	if err := c.revisit.Save(); err != nil {
+		fmt.Println("\r[Revisit Error]", err)
+	}
+	if err := c.recrawl.Save(); err != nil {
+		fmt.Println("\r[Recrawl Error]", err)
+	}
+}
+
+// contentHash fingerprints the visible text of a page, ignoring whitespace,
+// to tell whether it changed between visits
+func contentHash(doc *goquery.Document) uint64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(doc.Find("title").Text()))
+	for _, word := range strings.Fields(doc.Find("body").Text()) {
+		hash.Write([]byte(word))
+		hash.Write([]byte{' '})
+	}
+	return hash.Sum64()
+}
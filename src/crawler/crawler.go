@@ -1,119 +1,594 @@
 package crawler
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
-	"golang.org/x/time/rate"
 
+	"webcrawler/checkpoint"
 	"webcrawler/config"
+	"webcrawler/content"
+	"webcrawler/dedup"
+	"webcrawler/feed"
+	"webcrawler/filter"
+	"webcrawler/frontier"
+	"webcrawler/langid"
+	"webcrawler/recrawl"
+	"webcrawler/revisit"
+	"webcrawler/scheduler"
+	"webcrawler/sitemap"
 	"webcrawler/storage"
+	"webcrawler/structured"
 	"webcrawler/types"
 	"webcrawler/utils"
 )
 
+// Rejected URLs remembered for reporting before the set starts over
+const maxFilteredReports = 10000
+
 type Crawler struct {
 	config    *config.Config
-	visited   map[string]int // Track depth
+	seen      *frontier.Seen  // Every URL queued so far
+	hints     *frontier.Hints // Sitemap entries and feed publish dates for URLs until crawled
+	filtered  map[string]bool // Recently rejected URLs, so each is reported once
 	visitedMu sync.Mutex
-	queue     chan string
+	recrawl   *recrawl.Store       // Validators and outlinks from earlier crawls
+	revisit   *revisit.Schedule    // Next visit times, only in continuous mode
+	dedup     *dedup.Index         // Fingerprints of pages seen, nil if disabled
+	queue     *scheduler.Scheduler // Per-host politeness queues
+	client    *http.Client         // Shared by every fetch, for connection reuse
 	wg        sync.WaitGroup
 	processed int64
-	limiter   *rate.Limiter
 }
 
 func NewCrawler(cfg *config.Config) *Crawler {
-	return &Crawler{
-		config:  cfg,
-		visited: make(map[string]int),
-		queue:   make(chan string, cfg.QueueSize),
-		limiter: rate.NewLimiter(rate.Every(time.Second/time.Duration(cfg.RateLimit)), 1),
+	c := &Crawler{
+		config:   cfg,
+		seen:     frontier.NewSeen(cfg.SeenSize),
+		hints:    frontier.NewHints(),
+		filtered: make(map[string]bool),
+		queue:    scheduler.New(cfg),
+		recrawl:  recrawl.New(cfg.StateDir),
+	}
+	c.client = newClient(cfg, c.checkRedirect)
+	if cfg.NearDupDistance >= 0 {
+		c.dedup = dedup.NewIndex(cfg.NearDupDistance)
 	}
+	return c
+}
+
+// errNotModified is returned by fetch when a conditional request got a 304
+var errNotModified = errors.New("not modified")
+
+// fetchResult is a fetched page with the response headers extraction needs
+type fetchResult struct {
+	doc       *goquery.Document
+	header    http.Header
+	finalURL  string           // URL the response came from after redirects
+	redirects []types.Redirect // Hops followed to get there
+	encoding  string           // Character encoding the body was sent in
+	truncated bool             // Body was cut off at MaxBodySize
+	items     []types.FeedItem // Entries of an RSS or Atom feed, which has no doc
 }
 
 func (c *Crawler) Start(ctx context.Context, urls []string) {
-	// Initialize workers
+	c.startWorkers(ctx)
+
+	// Remember validators from earlier crawls so unchanged pages are skipped
+	c.loadValidators()
+
+	// Continue the last run if it left a checkpoint, otherwise start from the seeds
+	if !c.restore() {
+		c.openState(&checkpoint.State{})
+		for _, url := range urls {
+			c.addToQueue(url, nil, 0) // Start with depth 0
+		}
+	}
+
+	// A resumed crawl reads the sitemaps again in case the last run stopped
+	// before it had; the pages it already queued from them are seen
+	for _, url := range urls {
+		c.wg.Add(1)
+		go c.ingestSitemaps(ctx, url)
+	}
+
+	// Save the frontier if a signal stops the crawler
+	storage.OnShutdown(c.saveCheckpoint)
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	ticker := time.NewTicker(c.config.CheckpointInterval)
+	defer ticker.Stop()
+
+	// Wait for context cancellation or completion, checkpointing as we go
+	finished := false
+	for {
+		select {
+		case <-ticker.C:
+			c.saveCheckpoint()
+			continue
+		case <-ctx.Done():
+			fmt.Println("\nCrawling interrupted.")
+			c.saveCheckpoint()
+		case <-done:
+			fmt.Println("\nCrawling complete. Results saved to results.json")
+			if err := c.recrawl.Save(); err != nil {
+				fmt.Println("\r[Recrawl Error]", err)
+			}
+			if err := checkpoint.Clear(c.config.StateDir); err != nil {
+				fmt.Println("\r[Checkpoint Error]", err)
+			}
+			finished = true
+		}
+		break
+	}
+
+	c.queue.Close()
+	c.closeState()
+
+	// Nothing is left to resume, so the seen set and segments can go
+	if finished {
+		if err := os.RemoveAll(frontier.Dir(c.config.StateDir)); err != nil {
+			fmt.Println("\r[Checkpoint Error]", err)
+		}
+	}
+}
+
+// restore loads the last checkpoint and re-queues its pending URLs
+func (c *Crawler) restore() bool {
+	state, err := checkpoint.Load(c.config.StateDir)
+	if err != nil {
+		fmt.Println("\r[Checkpoint Error]", err)
+		return false
+	}
+	if state == nil {
+		return false
+	}
+
+	// Pages saved after the checkpoint must not be crawled again. They are
+	// matched on the URL they were requested as, which redirects change
+	after := make(map[string]types.PageData)
+	err = storage.ReadSaved(state.Saved, func(page types.PageData) {
+		after[requestedURL(page)] = page
+	})
+	if err != nil {
+		fmt.Println("\r[Checkpoint Error]", err)
+		return false
+	}
+	if !c.openState(state) {
+		return false
+	}
+
+	c.visitedMu.Lock()
+	c.processed = state.Processed
+	c.visitedMu.Unlock()
+
+	var dropped []frontier.Item
+	count, err := c.queue.Restore(state.Pending, state.Spilled, func(item frontier.Item) bool {
+		_, saved := after[item.URL]
+		if saved {
+			dropped = append(dropped, item)
+		}
+		return saved
+	})
+	if err != nil {
+		fmt.Println("\r[Checkpoint Error]", err)
+		return false
+	}
+	c.wg.Add(count)
+
+	// The links those pages queued went with the part of the seen set
+	// written after the checkpoint, so they are queued again
+	for _, item := range dropped {
+		c.requeueSaved(item, after)
+	}
+
+	fmt.Printf("Resuming from checkpoint saved at %s (%d URLs pending)\n", state.SavedAt.Format(time.RFC3339), count)
+	return true
+}
+
+// requeueSaved queues again what a page saved after the checkpoint queued,
+// from its record in after. Links to other pages saved since are followed
+// through their records rather than crawled twice
+func (c *Crawler) requeueSaved(item frontier.Item, after map[string]types.PageData) {
+	page, ok := after[item.URL]
+	if !ok {
+		return
+	}
+	delete(after, item.URL)
+
+	origin := item.Origin
+	if page.URL != item.URL {
+		c.claimFinalURL(page.URL)
+		origin, _ = c.redirectOrigin(page.URL, item.Origin)
+	}
+	requeue := func(link string, depth int) {
+		link = utils.Canonicalize(link)
+		if _, saved := after[link]; !saved {
+			c.addToQueue(link, &origin, depth)
+			return
+		}
+		linked, _ := c.linkOrigin(link, &origin)
+		c.claimFinalURL(link)
+		c.requeueSaved(frontier.Item{URL: link, Depth: depth, Origin: linked}, after)
+	}
+
+	// As when the page was crawled, the canonical keeps its depth
+	if page.Canonical != "" && page.Canonical != page.URL {
+		requeue(page.Canonical, item.Depth)
+	}
+	for _, link := range page.Queued {
+		requeue(link, item.Depth+1)
+	}
+}
+
+// requestedURL returns the URL a record's page was requested as. Records
+// from before Requested was stored start their redirect chain with it
+func requestedURL(page types.PageData) string {
+	if page.Requested != "" {
+		return page.Requested
+	}
+	if len(page.Redirects) > 0 {
+		return page.Redirects[0].URL
+	}
+	return page.URL
+}
+
+// openState opens the seen set and hints in the frontier directory at the
+// lengths a checkpoint recorded. If they cannot be opened a fresh crawl
+// keeps them in memory only, and checkpoints fail until it is fixed
+func (c *Crawler) openState(state *checkpoint.State) bool {
+	dir := frontier.Dir(c.config.StateDir)
+	err := os.MkdirAll(dir, 0755)
+	var seen *frontier.Seen
+	var hints *frontier.Hints
+	if err == nil {
+		seen, err = frontier.OpenSeen(filepath.Join(dir, "seen.bin"), state.Seen, c.config.SeenSize)
+	}
+	if err == nil {
+		hints, err = frontier.OpenHints(filepath.Join(dir, "hints.txt"), state.Hints)
+		if err != nil {
+			seen.Close()
+		}
+	}
+	if err != nil {
+		fmt.Println("\r[Checkpoint Error]", err)
+		return false
+	}
+
+	c.visitedMu.Lock()
+	c.seen.Close()
+	c.hints.Close()
+	c.seen, c.hints = seen, hints
+	c.visitedMu.Unlock()
+	return true
+}
+
+// closeState flushes the files of the seen set and hints and closes them
+func (c *Crawler) closeState() {
+	c.visitedMu.Lock()
+	defer c.visitedMu.Unlock()
+	if err := errors.Join(c.seen.Close(), c.hints.Close()); err != nil {
+		fmt.Println("\r[Checkpoint Error]", err)
+	}
+}
+
+// saveCheckpoint records where the frontier and seen set stand. The URLs
+// on disk are not copied, only the positions to resume them from
+func (c *Crawler) saveCheckpoint() {
+	c.visitedMu.Lock()
+	state := &checkpoint.State{
+		Processed: c.processed,
+		SavedAt:   time.Now(),
+	}
+	// Taken first, so a page still pending below but saved meanwhile has
+	// its record past the offset and is not crawled again on resume
+	saved, err := storage.Offset()
+	state.Saved = saved
+
+	// URLs are added to seen and queued under visitedMu, so the two agree
+	if err == nil {
+		state.Seen, err = c.seen.Sync()
+	}
+	if err == nil {
+		state.Hints, err = c.hints.Sync()
+	}
+	if err == nil {
+		state.Pending, state.Spilled, err = c.queue.Checkpoint()
+	}
+	c.visitedMu.Unlock()
+	if err != nil {
+		fmt.Println("\r[Checkpoint Error]", err)
+		return
+	}
+
+	// Anything no longer pending has been saved; make sure it is on disk
+	// before the checkpoint that forgets it
+	if err := storage.Flush(); err != nil {
+		fmt.Println("\r[Checkpoint Error]", err)
+		return
+	}
+	if err := checkpoint.Save(c.config.StateDir, state); err != nil {
+		fmt.Println("\r[Checkpoint Error]", err)
+		return
+	}
+	c.queue.Commit()
+	if err := c.recrawl.Save(); err != nil {
+		fmt.Println("\r[Recrawl Error]", err)
+	}
+}
+
+// loadValidators reads the recrawl store and imports the configured database
+func (c *Crawler) loadValidators() {
+	if err := c.recrawl.Load(); err != nil {
+		fmt.Println("\r[Recrawl Error]", err)
+	}
+	if c.config.RecrawlDatabase == "" {
+		return
+	}
+
+	count, err := c.recrawl.LoadAWF(c.config.RecrawlDatabase)
+	if err != nil {
+		fmt.Println("\r[Recrawl Error]", err)
+		return
+	}
+	fmt.Printf("Loaded validators for %d pages from %s\n", count, c.config.RecrawlDatabase)
+}
+
+// startWorkers launches the worker goroutines
+func (c *Crawler) startWorkers(ctx context.Context) {
 	for i := 0; i < c.config.WorkerCount; i++ {
 		go c.worker(ctx)
 	}
+}
 
-	// Add initial URLs to queue
-	for _, url := range urls {
-		c.addToQueue(url, 0) // Start with depth 0
+// ingestSitemaps queues the pages listed in a seed site's sitemaps
+func (c *Crawler) ingestSitemaps(ctx context.Context, seedURL string) {
+	defer c.wg.Done()
+
+	if c.config.SitemapLimit <= 0 {
+		return
+	}
+	if allowed, _ := utils.CheckURL(seedURL); !allowed {
+		return
+	}
+
+	get := c.sitemapGetter(seedURL)
+	remaining := c.config.SitemapLimit
+	for _, sitemapURL := range sitemap.Discover(seedURL, c.config.UserAgent) {
+		if remaining <= 0 || ctx.Err() != nil {
+			return
+		}
+
+		count, err := sitemap.Fetch(ctx, get, sitemapURL, remaining, func(entry types.SitemapEntry) {
+			c.addSitemapEntry(seedURL, entry)
+		})
+		remaining -= count
+		if err != nil {
+			fmt.Println("\r[Sitemap Failed]", sitemapURL, err)
+			continue
+		}
+		fmt.Printf("\r[Sitemap] %s (%d URLs)\n", sitemapURL, count)
+	}
+}
+
+// sitemapGetter fetches the sitemaps of a seed's site with the shared
+// client, waiting for the sitemap host's rate limit and taking one of its
+// in-flight slots as a page fetch would. The body is capped at MaxBodySize
+func (c *Crawler) sitemapGetter(seedURL string) sitemap.Getter {
+	origin := types.Origin{Seed: utils.Canonicalize(seedURL)}
+	return func(ctx context.Context, sitemapURL string) (io.ReadCloser, error) {
+		c.queue.SetCrawlDelay(scheduler.HostKey(sitemapURL), utils.CrawlDelay(sitemapURL, c.config.UserAgent))
+		if !c.queue.Acquire(ctx, sitemapURL) {
+			return nil, errors.New("crawler stopped")
+		}
+
+		// checkRedirect scopes redirects by the seed the sitemap belongs to
+		req, err := http.NewRequestWithContext(context.WithValue(ctx, originKey{}, origin), http.MethodGet, sitemapURL, nil)
+		if err != nil {
+			c.queue.Done(sitemapURL)
+			return nil, err
+		}
+		req.Header.Set("User-Agent", c.config.UserAgent)
+
+		resp, cancel, err := c.do(req)
+		if err != nil {
+			c.queue.Done(sitemapURL)
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			cancel()
+			c.queue.Done(sitemapURL)
+			return nil, fmt.Errorf("received HTTP status %d", resp.StatusCode)
+		}
+
+		timer := time.AfterFunc(c.config.BodyTimeout, cancel)
+		return &sitemapBody{
+			Reader: io.LimitReader(resp.Body, c.config.MaxBodySize),
+			release: func() {
+				timer.Stop()
+				resp.Body.Close()
+				cancel()
+				c.queue.Done(sitemapURL)
+			},
+		}, nil
 	}
+}
+
+// sitemapBody releases the host slot of a sitemap fetch once it is closed
+type sitemapBody struct {
+	io.Reader
+	release func()
+}
+
+func (b *sitemapBody) Close() error {
+	b.release()
+	return nil
+}
 
-	// Wait for context cancellation or completion
-	select {
-	case <-ctx.Done():
-		fmt.Println("\nCrawling interrupted.")
-	case <-func() chan struct{} {
-		done := make(chan struct{})
-		go func() {
-			c.wg.Wait()
-			close(done)
-		}()
-		return done
-	}():
-		fmt.Println("\nCrawling complete. Results saved to results.json")
+// addSitemapEntry queues a sitemap URL one hop below its seed and keeps
+// its lastmod, changefreq and priority for scheduling
+func (c *Crawler) addSitemapEntry(seedURL string, entry types.SitemapEntry) {
+	targetURL := utils.Canonicalize(entry.URL)
+	if targetURL == "" {
+		return
 	}
 
-	close(c.queue)
+	c.visitedMu.Lock()
+	if !c.seen.Has(targetURL) {
+		entry.URL = targetURL
+		c.hints.SetSitemap(entry)
+	}
+	c.visitedMu.Unlock()
+
+	c.addToQueue(targetURL, &types.Origin{Seed: utils.Canonicalize(seedURL)}, 1)
 }
 
 func (c *Crawler) worker(ctx context.Context) {
-	for url := range c.queue {
-		select {
-		case <-ctx.Done():
+	for {
+		// Blocks until whichever host is ready next
+		item, ok := c.queue.Next(ctx)
+		if !ok {
 			return
-		default:
-			c.fetchURL(url)
 		}
+		c.fetchURL(item)
+		c.queue.Done(item.URL)
 	}
 }
 
-func (c *Crawler) fetchURL(targetURL string) {
+func (c *Crawler) fetchURL(item frontier.Item) {
 	defer c.wg.Done()
 
-	// Respect rate limiter
-	c.limiter.Wait(context.Background())
+	targetURL, depth := item.URL, item.Depth
+
+	// A URL waiting for a retry keeps its hints
+	retrying := false
+	defer func() {
+		if retrying {
+			return
+		}
+		c.visitedMu.Lock()
+		c.hints.Forget(targetURL)
+		c.visitedMu.Unlock()
+
+		// A scheduled revisit that did not get recorded tries again later
+		if c.revisit != nil {
+			c.revisit.Release(targetURL, time.Now())
+		}
+	}()
 
 	// Update progress metrics
 	c.visitedMu.Lock()
 	processed := c.processed
 	c.visitedMu.Unlock()
-	utils.UpdateProgress(int64(len(c.queue)), processed)
+	utils.UpdateProgress(int64(c.queue.Len()), processed)
 
-	// Check robots.txt
-	if !utils.CanCrawl(targetURL, c.config.UserAgent) {
-		fmt.Println("\r[Blocked by robots.txt]", targetURL)
+	// Check URL rules and robots.txt
+	if reason := c.blockReason(targetURL); reason != "" {
+		if reason == blockedByRobots && c.waitForRobots(item) {
+			retrying = true
+			return
+		}
+		fmt.Printf("\r[%s] %s\n", reason, targetURL)
 		return
 	}
 
-	// Check blacklist
-	if utils.IsBlacklisted(targetURL) {
-		fmt.Println("\r[Blacklisted]", targetURL)
-		return
-	}
+	// Slow the host down if robots.txt asks for a Crawl-delay
+	c.queue.SetCrawlDelay(scheduler.HostKey(targetURL), utils.CrawlDelay(targetURL, c.config.UserAgent))
 
 	// Fetch and process the URL
-	doc, err := c.fetch(targetURL)
+	previous, _ := c.recrawl.Get(targetURL)
+	result, err := c.fetch(item, previous.Validators)
+	if errors.Is(err, errNotModified) {
+		c.saveUnchanged(item, previous, result.header)
+		return
+	}
 	if err != nil {
-		fmt.Println("\r[Failed]", targetURL, err)
+		if retrying = c.retry(item, err); !retrying {
+			fmt.Println("\r[Failed]", targetURL, err)
+		}
+		return
+	}
+
+	// After redirects the page is stored under the URL it ended on, unless
+	// that URL is crawled on its own already
+	pageURL, origin := targetURL, item.Origin
+	if len(result.redirects) > 0 {
+		pageURL = utils.Canonicalize(result.finalURL)
+		if pageURL != targetURL {
+			if !c.claimFinalURL(pageURL) {
+				fmt.Println("\r[Duplicate]", targetURL, "->", pageURL)
+				return
+			}
+			origin, _ = c.redirectOrigin(pageURL, item.Origin)
+		}
+	}
+
+	if result.doc == nil {
+		c.processFeed(item, result, origin)
 		return
 	}
 
-	// Extract and save data
-	data := c.extractData(targetURL, doc)
+	// Extract the data, or just the directives if the page opts out
+	robots := c.robotsDirectives(result.header, result.doc)
+	validators := validatorsFrom(result.header)
+	data := types.PageData{URL: pageURL, Robots: robots, Validators: validators}
+	nearDuplicate := false
+	if !robots.NoIndex {
+		data = c.extractData(pageURL, result.doc)
+		data.Encoding = result.encoding
+		data.Truncated = result.truncated
+		data.Canonical, data.Alternates = canonicalURLs(pageURL, result.header, result.doc)
+		data.Robots = robots
+		data.Validators = validators
+		if data.Structured.Published.IsZero() {
+			c.visitedMu.Lock()
+			data.Structured.Published = c.hints.Published(targetURL)
+			c.visitedMu.Unlock()
+		}
+		nearDuplicate = c.isNearDuplicate(&data)
+		if c.config.ImageHeaders > 0 {
+			c.fetchImageHeaders(targetURL, data.Images)
+		}
+
+		// The canonical is the same page, so it keeps this page's depth
+		if data.Canonical != "" && data.Canonical != pageURL {
+			c.addToQueue(data.Canonical, &origin, depth)
+		}
+	}
+
+	// Queue new links. A near-duplicate's links are the original's links
+	var links []string
+	if !robots.NoFollow && !nearDuplicate {
+		links = c.queueNewLinks(pageURL, origin, result.doc, depth)
+	}
+
+	// The record lists what it queued, for a crawl resumed after it is saved
+	data.Redirects = result.redirects
+	data.Requested = targetURL
+	data.Queued = links
 	storage.SaveData(data)
+	if robots.NoIndex {
+		fmt.Println("\r[Noindex]", pageURL)
+	}
 
-	// Queue new links
-	depth := c.visited[targetURL]
-	c.queueNewLinks(targetURL, doc, depth)
+	c.recrawl.Put(targetURL, recrawl.Entry{Validators: validators, Links: links})
+	c.recordVisit(revisit.Visit{URL: targetURL, Depth: depth, Hash: contentHash(result.doc), Origin: item.Origin})
 
 	fmt.Println("\r[Crawled]", targetURL)
 
@@ -123,24 +598,90 @@ func (c *Crawler) fetchURL(targetURL string) {
 	c.visitedMu.Unlock()
 }
 
-func (c *Crawler) fetch(targetURL string) (*goquery.Document, error) {
-	client := &http.Client{
-		Timeout: 10 * time.Second, // Set a timeout
+// saveUnchanged records a 304 and follows the links the page had last time
+func (c *Crawler) saveUnchanged(item frontier.Item, previous recrawl.Entry, header http.Header) {
+	targetURL, depth := item.URL, item.Depth
+
+	// A 304 may refresh the validators; keep the old ones where it does not
+	validators := validatorsFrom(header)
+	if validators.ETag == "" {
+		validators.ETag = previous.Validators.ETag
+	}
+	if validators.LastModified == "" {
+		validators.LastModified = previous.Validators.LastModified
 	}
 
-	resp, err := client.Get(targetURL)
+	lastModified, err := http.ParseTime(validators.LastModified)
+	if err != nil {
+		lastModified = time.Now()
+	}
+
+	// Feeds are not stored, so there is no record to refresh
+	if !previous.Feed {
+		storage.SaveData(types.PageData{
+			URL:          targetURL,
+			LastModified: lastModified,
+			Validators:   validators,
+			Unchanged:    true,
+			Requested:    targetURL,
+			Queued:       previous.Links,
+		})
+	}
+	for _, link := range previous.Links {
+		c.addToQueue(link, &item.Origin, depth+1)
+	}
+	c.recrawl.Put(targetURL, recrawl.Entry{Validators: validators, Links: previous.Links, Feed: previous.Feed})
+	c.recordVisit(revisit.Visit{URL: targetURL, Depth: depth, NotModified: true, Origin: item.Origin})
+
+	fmt.Println("\r[Unchanged]", targetURL)
+
+	c.visitedMu.Lock()
+	c.processed++
+	c.visitedMu.Unlock()
+}
+
+func validatorsFrom(header http.Header) types.Validators {
+	return types.Validators{
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+	}
+}
+
+func (c *Crawler) fetch(item frontier.Item, validators types.Validators) (*fetchResult, error) {
+	// checkRedirect scopes redirect targets by the origin of the page
+	ctx := context.WithValue(context.Background(), originKey{}, item.Origin)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, item.URL, nil)
 	if err != nil {
 		return nil, err
 	}
+	req.Header.Set("User-Agent", c.config.UserAgent)
+
+	// Ask the server to skip the body if nothing changed since the last crawl
+	if validators.ETag != "" {
+		req.Header.Set("If-None-Match", validators.ETag)
+	}
+	if validators.LastModified != "" {
+		req.Header.Set("If-Modified-Since", validators.LastModified)
+	}
+
+	resp, cancel, err := c.do(req)
+	if err != nil {
+		return nil, transportError(err)
+	}
+	defer cancel()
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified {
+		return &fetchResult{header: resp.Header}, errNotModified
+	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("received HTTP status %d", resp.StatusCode)
+		return nil, statusError(resp)
 	}
 
 	// Check content type
 	contentType := resp.Header.Get("Content-Type")
-	if !strings.Contains(contentType, "text/html") {
+	isFeed := feed.IsFeedType(contentType)
+	if !isFeed && !strings.Contains(contentType, "text/html") {
 		return nil, fmt.Errorf("unsupported content type: %s", contentType)
 	}
 
@@ -151,15 +692,40 @@ func (c *Crawler) fetch(targetURL string) (*goquery.Document, error) {
 		lastModifiedTime = time.Now() // Fallback to current time
 	}
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	body, truncated, err := c.readBody(resp, cancel)
+	if err != nil {
+		return nil, err
+	}
+	result := &fetchResult{
+		header:    resp.Header,
+		finalURL:  resp.Request.URL.String(),
+		redirects: redirectChain(resp),
+		truncated: truncated,
+	}
+
+	// A truncated feed still has the items before the cut
+	if isFeed {
+		result.items, err = feed.Parse(bytes.NewReader(body), result.finalURL)
+		if errors.Is(err, feed.ErrNotFeed) {
+			return nil, fmt.Errorf("unsupported content type: %s", contentType)
+		}
+		if err != nil && !truncated {
+			return nil, err
+		}
+		return result, nil
+	}
+	reader, encoding := decodeBody(body, contentType)
+	doc, err := goquery.NewDocumentFromReader(reader)
 	if err != nil {
 		return nil, err
 	}
+	result.doc = doc
+	result.encoding = encoding
 
 	// Store LastModified in the document's context
 	doc.Selection = doc.Selection.SetAttr("data-last-modified", lastModifiedTime.Format(time.RFC3339))
 
-	return doc, nil
+	return result, nil
 }
 
 func (c *Crawler) extractData(url string, doc *goquery.Document) types.PageData {
@@ -178,25 +744,23 @@ func (c *Crawler) extractData(url string, doc *goquery.Document) types.PageData
 		}
 	})
 
-	// Extract meta tags
+	// Extract meta tags, OpenGraph's property= ones included
 	var meta []types.Meta
 	doc.Find("meta").Each(func(i int, s *goquery.Selection) {
-		if name, exists := s.Attr("name"); exists {
+		name, exists := s.Attr("name")
+		if !exists {
+			name, exists = s.Attr("property")
+		}
+		if exists {
 			if content, exists := s.Attr("content"); exists {
 				meta = append(meta, types.Meta{Name: name, Content: content})
 			}
 		}
 	})
 
-	// Extract links
-	var links []string
-	doc.Find("a").Each(func(i int, s *goquery.Selection) {
-		if link, exists := s.Attr("href"); exists {
-			if absoluteURL := utils.ResolveURL(url, link); absoluteURL != "" {
-				links = append(links, absoluteURL)
-			}
-		}
-	})
+	// Extract links and images
+	links := extractLinks(url, doc)
+	images := extractImages(url, doc)
 
 	// Extract language
 	language := ""
@@ -214,6 +778,13 @@ func (c *Crawler) extractData(url string, doc *goquery.Document) types.PageData
 		}
 	})
 
+	// Extract main content
+	mainContent := content.Extract(doc)
+	fingerprint := dedup.Compute(mainContent.Text)
+
+	// The declared language is often missing or a CMS default
+	detected, confidence := langid.Detect(title + "\n" + description + "\n" + mainContent.Text)
+
 	// Extract LastModified
 	lastModified := doc.Selection.AttrOr("data-last-modified", time.Now().Format(time.RFC3339))
 	lastModifiedTime, _ := time.Parse(time.RFC3339, lastModified)
@@ -224,31 +795,114 @@ func (c *Crawler) extractData(url string, doc *goquery.Document) types.PageData
 		Description:  description,
 		Meta:         meta,
 		LastModified: lastModifiedTime,
-		// Links:        links,
+		Links:        links,
+		Images:       images,
 		Language:     language,
+		Detected:     detected,
+		Confidence:   confidence,
 		Favicon:      favicon,
+		Content:      mainContent.Text,
+		WordCount:    mainContent.WordCount,
+		TextRatio:    mainContent.TextRatio,
+		Structured:   structured.Extract(url, doc),
+		ContentHash:  fingerprint.Hash,
+		SimHash:      fingerprint.SimHash,
 	}
 }
 
-func (c *Crawler) queueNewLinks(baseURL string, doc *goquery.Document, depth int) {
+// extractLinks collects the page's outgoing web links for the link graph
+func extractLinks(pageURL string, doc *goquery.Document) []types.Link {
+	pageHost := scheduler.HostKey(utils.Canonicalize(pageURL))
+
+	var links []types.Link
+	doc.Find("a[href]").Each(func(i int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		target := utils.Canonicalize(utils.ResolveURL(pageURL, href))
+		if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+			return
+		}
+
+		text := strings.Join(strings.Fields(s.Text()), " ")
+		if text == "" {
+			text = strings.TrimSpace(s.Find("img[alt]").AttrOr("alt", ""))
+		}
+
+		links = append(links, types.Link{
+			URL:      target,
+			Text:     text,
+			Rel:      strings.Fields(strings.ToLower(s.AttrOr("rel", ""))),
+			Internal: scheduler.HostKey(target) == pageHost,
+		})
+	})
+	return links
+}
+
+// queueNewLinks queues every followable link on the page and returns them
+func (c *Crawler) queueNewLinks(baseURL string, origin types.Origin, doc *goquery.Document, depth int) []string {
+	var links []string
 	doc.Find("a").Each(func(i int, s *goquery.Selection) {
+		if hasRel(s.AttrOr("rel", ""), "nofollow") {
+			return
+		}
 		if link, exists := s.Attr("href"); exists {
 			if absoluteURL := utils.ResolveURL(baseURL, link); absoluteURL != "" {
-				c.addToQueue(absoluteURL, depth+1)
+				c.addToQueue(absoluteURL, &origin, depth+1)
+				links = append(links, absoluteURL)
 				fmt.Println("\r[Queued]", absoluteURL)
 			}
 		}
 	})
+
+	// An advertised feed is another view of this page, so it keeps its depth
+	for _, feedURL := range feed.Discover(baseURL, doc) {
+		c.addToQueue(feedURL, &origin, depth)
+		links = append(links, feedURL)
+	}
+	return links
 }
 
-func (c *Crawler) addToQueue(targetURL string, depth int) {
+// addToQueue queues a URL found on a page with origin from, or a seed if
+// from is nil. It is dropped if it was seen before, a rule rejects it, it is
+// too deep, or it lies more off-site hops outside the seed's scope than the
+// scope allows
+func (c *Crawler) addToQueue(targetURL string, from *types.Origin, depth int) {
+	// Dedup on the canonical form so spelling variants are crawled once
+	targetURL = utils.Canonicalize(targetURL)
+	if targetURL == "" {
+		return
+	}
+
 	c.visitedMu.Lock()
 	defer c.visitedMu.Unlock()
 
-	if _, seen := c.visited[targetURL]; !seen && !utils.IsBlacklisted(targetURL) && depth < c.config.MaxDepth {
-		c.visited[targetURL] = depth
-		c.wg.Add(1)
-		c.queue <- targetURL
-		utils.UpdateProgress(int64(len(c.queue)), c.processed)
+	if c.seen.Has(targetURL) || depth >= c.config.MaxDepth {
+		return
+	}
+	if allowed, rule := utils.CheckURL(targetURL); !allowed {
+		c.reportFiltered(targetURL, rule)
+		return
+	}
+	origin, inScope := c.linkOrigin(targetURL, from)
+	if !inScope {
+		return
+	}
+
+	c.seen.Add(targetURL)
+	c.wg.Add(1)
+	c.queue.Push(frontier.Item{URL: targetURL, Depth: depth, Origin: origin})
+	utils.UpdateProgress(int64(c.queue.Len()), c.processed)
+}
+
+// reportFiltered logs a URL a config rule rejected, once while it is in
+// the bounded set of recent rejections. mailto:, tel: and other links that
+// are not web pages are too common to report. Must hold visitedMu
+func (c *Crawler) reportFiltered(targetURL, rule string) {
+	if rule == filter.UnsupportedScheme || c.filtered[targetURL] {
+		return
+	}
+	if len(c.filtered) >= maxFilteredReports {
+		clear(c.filtered)
 	}
+	c.filtered[targetURL] = true
+	fmt.Printf("\r[Filtered] %s (rule %s)\n", targetURL, rule)
 }
@@ -0,0 +1,26 @@
+package crawler
+
+import (
+	"fmt"
+
+	"webcrawler/dedup"
+	"webcrawler/scheduler"
+	"webcrawler/types"
+)
+
+// isNearDuplicate reports whether an earlier page on the same host cluster
+// has the same or nearly the same main text. Mirrors, printer-friendly
+// copies and session-id URLs are saved, but their links are not followed
+func (c *Crawler) isNearDuplicate(data *types.PageData) bool {
+	if c.dedup == nil || data.WordCount < dedup.MinWords {
+		return false
+	}
+
+	cluster := dedup.Cluster(scheduler.HostKey(data.URL))
+	fingerprint := dedup.Fingerprint{Hash: data.ContentHash, SimHash: data.SimHash}
+	original, found := c.dedup.Check(cluster, data.URL, fingerprint)
+	if found {
+		fmt.Println("\r[Near Duplicate]", data.URL, "~", original)
+	}
+	return found
+}
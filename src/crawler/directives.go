@@ -0,0 +1,78 @@
+package crawler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"webcrawler/types"
+)
+
+// robotsDirectives combines the page's robots meta tags with its
+// X-Robots-Tag headers. Generic rules and rules naming our user agent both
+// apply, and a directive from any source wins
+func (c *Crawler) robotsDirectives(header http.Header, doc *goquery.Document) types.RobotsDirectives {
+	var directives types.RobotsDirectives
+	agent := strings.ToLower(c.config.UserAgent)
+
+	doc.Find("meta[name][content]").Each(func(i int, s *goquery.Selection) {
+		name := strings.ToLower(strings.TrimSpace(s.AttrOr("name", "")))
+		if name == "robots" || name == agent {
+			applyDirectives(&directives, s.AttrOr("content", ""))
+		}
+	})
+
+	for _, value := range header.Values("X-Robots-Tag") {
+		// "googlebot: noindex" targets one bot; "noindex" or
+		// "unavailable_after: <date>" are for everyone
+		if name, rest, found := strings.Cut(value, ":"); found && !isDirective(name) {
+			if strings.ToLower(strings.TrimSpace(name)) != agent {
+				continue
+			}
+			value = rest
+		}
+		applyDirectives(&directives, value)
+	}
+	return directives
+}
+
+// applyDirectives sets the directives named in a comma-separated list
+func applyDirectives(directives *types.RobotsDirectives, list string) {
+	for _, directive := range strings.Split(list, ",") {
+		switch strings.ToLower(strings.TrimSpace(directive)) {
+		case "noindex":
+			directives.NoIndex = true
+		case "nofollow":
+			directives.NoFollow = true
+		case "none":
+			directives.NoIndex = true
+			directives.NoFollow = true
+		case "noarchive", "nocache":
+			directives.NoArchive = true
+		case "nosnippet":
+			directives.NoSnippet = true
+		}
+	}
+}
+
+// isDirective tells a directive apart from a user agent name before a colon
+func isDirective(name string) bool {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "all", "noindex", "nofollow", "none", "noarchive", "nocache", "nosnippet",
+		"notranslate", "noimageindex", "indexifembedded", "unavailable_after",
+		"max-snippet", "max-image-preview", "max-video-preview":
+		return true
+	}
+	return false
+}
+
+// hasRel reports whether a space-separated rel attribute contains value
+func hasRel(rel, value string) bool {
+	for _, token := range strings.Fields(strings.ToLower(rel)) {
+		if token == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package crawler
+
+import (
+	"fmt"
+	"hash/fnv"
+
+	"webcrawler/frontier"
+	"webcrawler/recrawl"
+	"webcrawler/revisit"
+	"webcrawler/types"
+	"webcrawler/utils"
+)
+
+// processFeed queues the articles of an RSS or Atom feed one hop below it
+// and remembers their publish dates for when they are crawled. A feed is
+// not stored as a page; in continuous mode its revisits pick up new items.
+// origin is the feed's, after any redirect
+func (c *Crawler) processFeed(feedItem frontier.Item, result *fetchResult, origin types.Origin) {
+	targetURL, depth := feedItem.URL, feedItem.Depth
+
+	hash := fnv.New64a()
+	links := make([]string, 0, len(result.items))
+	for _, item := range result.items {
+		itemURL := utils.Canonicalize(item.URL)
+		if itemURL == "" {
+			continue
+		}
+
+		// A revisited feed lists items crawled already; they need no date
+		if !item.Published.IsZero() {
+			c.visitedMu.Lock()
+			if !c.seen.Has(itemURL) {
+				c.hints.SetPublished(itemURL, item.Published)
+			}
+			c.visitedMu.Unlock()
+		}
+		c.addToQueue(itemURL, &origin, depth+1)
+		links = append(links, itemURL)
+
+		hash.Write([]byte(itemURL))
+		hash.Write([]byte{'\n'})
+	}
+
+	c.recrawl.Put(targetURL, recrawl.Entry{Validators: validatorsFrom(result.header), Links: links, Feed: true})
+	c.recordVisit(revisit.Visit{URL: targetURL, Depth: depth, Hash: hash.Sum64(), Origin: feedItem.Origin})
+
+	fmt.Printf("\r[Feed] %s (%d items)\n", targetURL, len(links))
+
+	c.visitedMu.Lock()
+	c.processed++
+	c.visitedMu.Unlock()
+}
@@ -0,0 +1,240 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"webcrawler/scheduler"
+	"webcrawler/types"
+	"webcrawler/utils"
+)
+
+const (
+	// Longest an image lookup waits for its host's rate limit, while the
+	// page that found it keeps its own host's slot
+	imageHeaderWait = 5 * time.Second
+
+	// Image headers remembered before the cache starts over
+	maxImageHeaders = 10000
+)
+
+// imageHeader is what a HEAD request told about an image
+type imageHeader struct {
+	contentType string
+	size        int64
+}
+
+var (
+	// Image headers already fetched, so a logo on every page is asked for once
+	imageHeaders   = make(map[string]imageHeader)
+	imageHeadersMu sync.Mutex
+)
+
+// extractImages collects the page's images for image search: every <img>
+// with the <source> candidates of its <picture>, alt and title text, size
+// attributes and the caption of the figure it is in
+func extractImages(pageURL string, doc *goquery.Document) []types.Image {
+	var images []types.Image
+	seen := make(map[string]bool)
+
+	doc.Find("img").Each(func(i int, s *goquery.Selection) {
+		var sources []types.ImageSource
+
+		// A <picture> offers alternatives before the <img> fallback
+		if picture := s.Parent(); goquery.NodeName(picture) == "picture" {
+			picture.ChildrenFiltered("source").Each(func(j int, source *goquery.Selection) {
+				srcset := source.AttrOr("srcset", source.AttrOr("data-srcset", ""))
+				for _, candidate := range parseSrcset(pageURL, srcset) {
+					candidate.Media = strings.TrimSpace(source.AttrOr("media", ""))
+					candidate.Type = strings.TrimSpace(source.AttrOr("type", ""))
+					sources = append(sources, candidate)
+				}
+			})
+		}
+		sources = append(sources, parseSrcset(pageURL, s.AttrOr("srcset", s.AttrOr("data-srcset", "")))...)
+
+		// Lazy loaders keep the real image in data-src until it scrolls in
+		src := imageURL(pageURL, s.AttrOr("data-src", ""))
+		if src == "" {
+			src = imageURL(pageURL, s.AttrOr("src", ""))
+		}
+		if src == "" {
+			src = largestSource(sources)
+		}
+		if src == "" || seen[src] {
+			return
+		}
+		seen[src] = true
+
+		images = append(images, types.Image{
+			URL:     src,
+			Sources: sources,
+			Alt:     strings.TrimSpace(s.AttrOr("alt", "")),
+			Title:   strings.TrimSpace(s.AttrOr("title", "")),
+			Width:   dimension(s.AttrOr("width", "")),
+			Height:  dimension(s.AttrOr("height", "")),
+			Caption: strings.Join(strings.Fields(s.Closest("figure").Find("figcaption").First().Text()), " "),
+		})
+	})
+	return images
+}
+
+// parseSrcset splits a srcset into its candidates. URLs may contain commas,
+// so a candidate ends at whitespace, and a comma only ends the descriptors
+func parseSrcset(pageURL, srcset string) []types.ImageSource {
+	var sources []types.ImageSource
+	rest := srcset
+	for {
+		rest = strings.TrimLeftFunc(rest, func(r rune) bool { return unicode.IsSpace(r) || r == ',' })
+		if rest == "" {
+			return sources
+		}
+
+		end := strings.IndexFunc(rest, unicode.IsSpace)
+		if end < 0 {
+			end = len(rest)
+		}
+		candidate := rest[:end]
+		rest = rest[end:]
+
+		descriptor := ""
+		if strings.HasSuffix(candidate, ",") {
+			candidate = strings.TrimRight(candidate, ",")
+		} else {
+			comma := strings.IndexByte(rest, ',')
+			if comma < 0 {
+				comma = len(rest)
+			}
+			descriptor = strings.TrimSpace(rest[:comma])
+			rest = rest[comma:]
+		}
+
+		if url := imageURL(pageURL, candidate); url != "" {
+			sources = append(sources, types.ImageSource{URL: url, Descriptor: descriptor})
+		}
+	}
+}
+
+// largestSource picks the candidate with the highest width or density
+// descriptor, or the first one if none has a descriptor
+func largestSource(sources []types.ImageSource) string {
+	best, bestSize := "", -1.0
+	for _, source := range sources {
+		size := 0.0
+		if d := source.Descriptor; len(d) > 1 {
+			size, _ = strconv.ParseFloat(d[:len(d)-1], 64)
+			if strings.HasSuffix(d, "x") {
+				size *= 1000 // Densities are small numbers, widths are pixels
+			}
+		}
+		if size > bestSize {
+			best, bestSize = source.URL, size
+		}
+	}
+	return best
+}
+
+// imageURL resolves an image reference, skipping inline data: images
+func imageURL(pageURL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	resolved := utils.ResolveURL(pageURL, ref)
+	if !strings.HasPrefix(resolved, "http://") && !strings.HasPrefix(resolved, "https://") {
+		return ""
+	}
+	return resolved
+}
+
+// dimension reads a width or height attribute, which may carry a px suffix
+func dimension(value string) int {
+	n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(value), "px"))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// fetchImageHeaders sends HEAD requests for up to ImageHeaders images of a
+// page to record their content type and size. Images robots.txt or a URL
+// rule rules out are skipped. Requests go through the scheduler, so image
+// hosts get the same politeness as pages; an image whose host is not ready
+// in time is skipped
+func (c *Crawler) fetchImageHeaders(heldURL string, images []types.Image) {
+	requests := 0
+	for i := range images {
+		imageHeadersMu.Lock()
+		known, ok := imageHeaders[images[i].URL]
+		imageHeadersMu.Unlock()
+		if ok {
+			images[i].ContentType, images[i].Size = known.contentType, known.size
+			continue
+		}
+		if requests >= c.config.ImageHeaders {
+			continue
+		}
+		if c.blockReason(images[i].URL) != "" {
+			continue
+		}
+		requests++
+
+		resp, ok := c.headImage(heldURL, images[i].URL)
+		if !ok {
+			continue
+		}
+		images[i].ContentType = resp.Header.Get("Content-Type")
+		if resp.ContentLength > 0 {
+			images[i].Size = resp.ContentLength
+		}
+
+		imageHeadersMu.Lock()
+		if len(imageHeaders) >= maxImageHeaders {
+			clear(imageHeaders)
+		}
+		imageHeaders[images[i].URL] = imageHeader{contentType: images[i].ContentType, size: images[i].Size}
+		imageHeadersMu.Unlock()
+	}
+}
+
+// headImage sends a HEAD request for an image once its host's rate limit,
+// Crawl-delay and in-flight cap allow it. An image on the host of heldURL,
+// whose slot the worker already holds, waits on the rate limit alone.
+// Returns false on any failure
+func (c *Crawler) headImage(heldURL, imageURL string) (*http.Response, bool) {
+	key := scheduler.HostKey(imageURL)
+	c.queue.SetCrawlDelay(key, utils.CrawlDelay(imageURL, c.config.UserAgent))
+
+	ctx, stop := context.WithTimeout(context.Background(), imageHeaderWait)
+	defer stop()
+	if key == scheduler.HostKey(heldURL) {
+		if !c.queue.Throttle(ctx, imageURL) {
+			return nil, false
+		}
+	} else {
+		if !c.queue.Acquire(ctx, imageURL) {
+			return nil, false
+		}
+		defer c.queue.Done(imageURL)
+	}
+
+	req, err := http.NewRequest(http.MethodHead, imageURL, nil)
+	if err != nil {
+		return nil, false
+	}
+	req.Header.Set("User-Agent", c.config.UserAgent)
+	resp, cancel, err := c.do(req)
+	if err != nil {
+		return nil, false
+	}
+	resp.Body.Close()
+	cancel()
+	return resp, resp.StatusCode == http.StatusOK
+}
@@ -0,0 +1,64 @@
+package crawler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"webcrawler/types"
+	"webcrawler/utils"
+)
+
+// errRedirectRefused is returned when a redirect hop fails a crawl check
+var errRedirectRefused = errors.New("redirect refused")
+
+// blockedByRobots is the reason blockReason gives for a robots.txt disallow
+const blockedByRobots = "Blocked by robots.txt"
+
+// originKey carries the origin of the page being fetched in its request's
+// context, for checkRedirect
+type originKey struct{}
+
+// blockReason runs the checks every URL must pass before it is fetched and
+// returns why it may not be, or "" if it may
+func (c *Crawler) blockReason(targetURL string) string {
+	if !strings.HasPrefix(targetURL, "http://") && !strings.HasPrefix(targetURL, "https://") {
+		return "Unsupported scheme"
+	}
+	if allowed, rule := utils.CheckURL(targetURL); !allowed {
+		return "Blocked by rule " + rule
+	}
+	if !utils.CanCrawl(targetURL, c.config.UserAgent) {
+		return blockedByRobots
+	}
+	return ""
+}
+
+// checkRedirect applies the same checks as the first request to every
+// redirect target, the seed's scope included. The hops are read back from
+// the response afterwards
+func (c *Crawler) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) > c.config.MaxRedirects {
+		return fmt.Errorf("%w: more than %d hops", errRedirectRefused, c.config.MaxRedirects)
+	}
+	target := req.URL.String()
+	if reason := c.blockReason(target); reason != "" {
+		return fmt.Errorf("%w: %s: %s", errRedirectRefused, strings.ToLower(reason), target)
+	}
+
+	page, _ := req.Context().Value(originKey{}).(types.Origin)
+	if _, inScope := c.redirectOrigin(utils.Canonicalize(target), page); !inScope {
+		return fmt.Errorf("%w: out of scope: %s", errRedirectRefused, target)
+	}
+	return nil
+}
+
+// claimFinalURL marks the URL a redirect chain ended on as seen. Returns
+// false if it was already crawled or queued, so this copy should be dropped
+func (c *Crawler) claimFinalURL(finalURL string) bool {
+	c.visitedMu.Lock()
+	defer c.visitedMu.Unlock()
+
+	return c.seen.Add(finalURL)
+}
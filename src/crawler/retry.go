@@ -0,0 +1,126 @@
+package crawler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"webcrawler/frontier"
+	"webcrawler/scheduler"
+	"webcrawler/utils"
+)
+
+// retryableError is a failure worth trying again later
+type retryableError struct {
+	err        error
+	retryAfter time.Duration // From the Retry-After header, zero if absent
+	throttled  bool          // The server said we are going too fast (429)
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// statusError turns a non-200 response into an error, marking 429 and 5xx
+// gateway errors as retryable
+func statusError(resp *http.Response) error {
+	err := fmt.Errorf("received HTTP status %d", resp.StatusCode)
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return &retryableError{
+			err:        err,
+			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+			throttled:  resp.StatusCode == http.StatusTooManyRequests,
+		}
+	}
+	return err
+}
+
+// transportError marks timeouts and dropped connections as retryable
+func transportError(err error) error {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF) {
+		return &retryableError{err: err}
+	}
+	return err
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as a date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0)
+	}
+	return 0
+}
+
+// backoff is the exponential delay before a retry, with jitter so that
+// retries against one host spread out
+func (c *Crawler) backoff(attempt int) time.Duration {
+	delay := c.config.RetryBaseDelay << (attempt - 1)
+	if delay <= 0 || delay > c.config.RetryMaxDelay {
+		delay = c.config.RetryMaxDelay
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// retry puts a failed URL back through the scheduler after a backoff.
+// Returns false when the URL has used up its attempts
+func (c *Crawler) retry(item frontier.Item, err error) bool {
+	var retryable *retryableError
+	if !errors.As(err, &retryable) {
+		return false
+	}
+	if retryable.throttled {
+		c.queue.SlowDown(scheduler.HostKey(item.URL))
+	}
+
+	item.Attempts++
+	if item.Attempts > c.config.MaxRetries {
+		return false
+	}
+
+	delay := max(c.backoff(item.Attempts), retryable.retryAfter)
+	if delay > c.config.RetryMaxDelay {
+		return false // The server wants us gone for longer than we wait
+	}
+
+	c.wg.Add(1)
+	c.queue.PushAfter(item, delay)
+	fmt.Printf("\r[Retrying] %s in %s (attempt %d/%d): %v\n", item.URL, delay.Round(time.Millisecond), item.Attempts, c.config.MaxRetries, err)
+	return true
+}
+
+// waitForRobots puts a URL back through the scheduler until its host's
+// robots.txt is fetched again, if the last fetch failed. Returns false if
+// robots.txt was read fine or the URL has used up its attempts
+func (c *Crawler) waitForRobots(item frontier.Item) bool {
+	delay := utils.RobotsRetryAfter(item.URL)
+	if delay <= 0 {
+		return false
+	}
+
+	item.Attempts++
+	if item.Attempts > c.config.MaxRetries {
+		return false
+	}
+
+	c.wg.Add(1)
+	c.queue.PushAfter(item, delay)
+	fmt.Printf("\r[Robots Unavailable] %s, retrying in %s (attempt %d/%d)\n", item.URL, delay.Round(time.Second), item.Attempts, c.config.MaxRetries)
+	return true
+}
@@ -0,0 +1,101 @@
+package crawler
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+
+	"webcrawler/config"
+	"webcrawler/types"
+	"webcrawler/utils"
+)
+
+// scopeFor returns a seed's scope from config.rcf, with the crawl-wide
+// defaults for anything it leaves unset
+func (c *Crawler) scopeFor(seed string) config.Scope {
+	scope, _ := utils.SeedScope(seed)
+	if scope.Mode == "" {
+		scope.Mode = c.config.Scope
+	}
+	if scope.MaxOffsiteHops < 0 {
+		scope.MaxOffsiteHops = c.config.MaxOffsiteHops
+	}
+	return scope
+}
+
+// linkOrigin works out the origin of a URL linked from a page with origin
+// from, and whether following the link stays within the seed's off-site hop
+// limit. A link from nil is a seed
+func (c *Crawler) linkOrigin(targetURL string, from *types.Origin) (types.Origin, bool) {
+	if from == nil {
+		return types.Origin{Seed: targetURL}, true
+	}
+	parent := *from
+
+	scope := c.scopeFor(parent.Seed)
+	origin := types.Origin{Seed: parent.Seed}
+	if !onSite(scope, parent.Seed, targetURL) {
+		origin.Hops = parent.Hops + 1
+	}
+	return origin, origin.Hops <= scope.MaxOffsiteHops
+}
+
+// redirectOrigin is linkOrigin for a redirect target, which stands in for
+// the page with origin page that was asked for: leaving the scope costs a
+// hop, but moving between off-site pages does not
+func (c *Crawler) redirectOrigin(targetURL string, page types.Origin) (types.Origin, bool) {
+	scope := c.scopeFor(page.Seed)
+	origin := types.Origin{Seed: page.Seed}
+	if !onSite(scope, page.Seed, targetURL) {
+		origin.Hops = max(page.Hops, 1)
+	}
+	return origin, origin.Hops <= scope.MaxOffsiteHops
+}
+
+// onSite reports whether a URL is within a seed's scope
+func onSite(scope config.Scope, seed, targetURL string) bool {
+	if scope.Mode == "any" {
+		return true
+	}
+
+	host := hostname(targetURL)
+	for _, domain := range scope.Allow {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+
+	seedHost := hostname(seed)
+	switch scope.Mode {
+	case "host":
+		return host == seedHost
+	case "domain":
+		return registrableDomain(host) == registrableDomain(seedHost)
+	}
+	return false
+}
+
+// hostname returns a URL's lowercased host without its port
+func hostname(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Hostname())
+}
+
+// registrableDomain returns the part of a host its owner registered, such
+// as example.co.uk for www.example.co.uk, per the embedded Public Suffix
+// List. IP addresses and bare suffixes are their own domain
+func registrableDomain(host string) string {
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
+}
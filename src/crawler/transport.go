@@ -0,0 +1,79 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"webcrawler/config"
+	"webcrawler/types"
+)
+
+// newClient builds the HTTP client every fetch shares, so connections to a
+// host are reused across pages instead of being dialed for each one
+func newClient(cfg *config.Config, checkRedirect func(*http.Request, []*http.Request) error) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   cfg.DialTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true, // A custom dialer turns HTTP/2 off unless asked
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.IdleConnsPerHost,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   cfg.TLSTimeout,
+		ResponseHeaderTimeout: cfg.HeaderTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+	return &http.Client{Transport: transport, CheckRedirect: checkRedirect}
+}
+
+// do sends a request with the shared client. The returned cancel func must
+// be called once the body has been read
+func (c *Crawler) do(req *http.Request) (*http.Response, context.CancelFunc, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	return resp, cancel, nil
+}
+
+// readBody reads at most MaxBodySize bytes of a response within BodyTimeout.
+// A longer body is cut off and reported as truncated rather than read whole
+func (c *Crawler) readBody(resp *http.Response, cancel context.CancelFunc) ([]byte, bool, error) {
+	timer := time.AfterFunc(c.config.BodyTimeout, cancel)
+	body, err := io.ReadAll(io.LimitReader(resp.Body, c.config.MaxBodySize+1))
+	expired := !timer.Stop()
+
+	// A body read in full counts even if the timer fired just after
+	if err != nil {
+		if expired {
+			return nil, false, &retryableError{err: fmt.Errorf("body not read within %s", c.config.BodyTimeout)}
+		}
+		return nil, false, transportError(err)
+	}
+
+	if int64(len(body)) > c.config.MaxBodySize {
+		return body[:c.config.MaxBodySize], true, nil
+	}
+	return body, false, nil
+}
+
+// redirectChain lists the hops that led to a response, oldest first
+func redirectChain(resp *http.Response) []types.Redirect {
+	var chain []types.Redirect
+	for req := resp.Request; req.Response != nil; req = req.Response.Request {
+		chain = append([]types.Redirect{{
+			URL:    req.Response.Request.URL.String(),
+			Status: req.Response.StatusCode,
+		}}, chain...)
+	}
+	return chain
+}
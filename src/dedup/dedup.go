@@ -0,0 +1,199 @@
+package dedup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/fnv"
+	"math/bits"
+	"net"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const (
+	shingleSize = 3 // Words per feature, so reordered boilerplate still differs
+	bands       = 4 // SimHash is split into this many bands for lookup
+	bandBits    = 64 / bands
+
+	// MinWords is the least text worth comparing; stubs and empty pages
+	// would all look alike
+	MinWords = 20
+)
+
+// Fingerprint is the exact and near-duplicate signature of a page's text
+type Fingerprint struct {
+	Hash    string // SHA-256 of the normalized text, hex encoded
+	SimHash uint64 // 64-bit SimHash of word shingles
+}
+
+// Compute fingerprints extracted page text. Case, punctuation and
+// whitespace are ignored, so trivially reformatted copies hash the same
+func Compute(text string) Fingerprint {
+	words := normalize(text)
+	sum := sha256.Sum256([]byte(strings.Join(words, " ")))
+	return Fingerprint{
+		Hash:    hex.EncodeToString(sum[:]),
+		SimHash: SimHash(words),
+	}
+}
+
+// SimHash returns the Charikar SimHash of the word shingles. Pages that
+// share most of their shingles differ in only a few bits
+func SimHash(words []string) uint64 {
+	if len(words) == 0 {
+		return 0
+	}
+
+	var weights [64]int
+	add := func(feature string) {
+		hash := fnv.New64a()
+		hash.Write([]byte(feature))
+		sum := hash.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	if len(words) < shingleSize {
+		add(strings.Join(words, " "))
+	}
+	for i := 0; i+shingleSize <= len(words); i++ {
+		add(strings.Join(words[i:i+shingleSize], " "))
+	}
+
+	var simhash uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			simhash |= 1 << bit
+		}
+	}
+	return simhash
+}
+
+// Distance is the number of bits two SimHashes differ in
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Cluster names the group of hosts a page is compared within: the host
+// without its port or a leading www.
+func Cluster(host string) string {
+	host = strings.ToLower(host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimPrefix(host, "www.")
+}
+
+// normalize lowercases the text and splits it into words, dropping
+// punctuation
+func normalize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+type entry struct {
+	url     string
+	simhash uint64
+}
+
+// Index remembers the fingerprints of pages seen so far, per host cluster.
+// It is safe for concurrent use
+type Index struct {
+	maxDistance int
+	mu          sync.Mutex
+	exact       map[string]string             // cluster + hash -> first URL
+	bands       map[string]map[uint64][]entry // cluster -> band key -> pages
+	pages       map[string]Fingerprint        // cluster + URL -> fingerprint it is indexed under
+}
+
+// NewIndex creates an index that treats SimHashes at most maxDistance bits
+// apart as near-duplicates. Lookups are exact for distances below the
+// number of bands; larger distances are only found when a band matches
+func NewIndex(maxDistance int) *Index {
+	return &Index{
+		maxDistance: maxDistance,
+		exact:       make(map[string]string),
+		bands:       make(map[string]map[uint64][]entry),
+		pages:       make(map[string]Fingerprint),
+	}
+}
+
+// Check looks for an earlier page in the cluster with the same or nearly
+// the same text, returning its URL, and adds the page if there is none.
+// A URL never matches itself, so revisits are not duplicates, and a
+// revisit replaces the URL's entry rather than adding another
+func (idx *Index) Check(cluster, url string, fp Fingerprint) (string, bool) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if original, ok := idx.exact[cluster+" "+fp.Hash]; ok && original != url {
+		return original, true
+	}
+
+	clusterBands := idx.bands[cluster]
+	if clusterBands == nil {
+		clusterBands = make(map[uint64][]entry)
+		idx.bands[cluster] = clusterBands
+	}
+	for band := 0; band < bands; band++ {
+		for _, e := range clusterBands[bandKey(fp.SimHash, band)] {
+			if e.url != url && Distance(e.simhash, fp.SimHash) <= idx.maxDistance {
+				return e.url, true
+			}
+		}
+	}
+
+	if previous, ok := idx.pages[cluster+" "+url]; ok {
+		if previous == fp {
+			return "", false
+		}
+		idx.remove(cluster, url, previous)
+	}
+	idx.pages[cluster+" "+url] = fp
+
+	if _, ok := idx.exact[cluster+" "+fp.Hash]; !ok {
+		idx.exact[cluster+" "+fp.Hash] = url
+	}
+	for band := 0; band < bands; band++ {
+		key := bandKey(fp.SimHash, band)
+		clusterBands[key] = append(clusterBands[key], entry{url: url, simhash: fp.SimHash})
+	}
+	return "", false
+}
+
+// remove drops the entries a URL was indexed under before its page
+// changed. Must hold idx.mu
+func (idx *Index) remove(cluster, url string, fp Fingerprint) {
+	if idx.exact[cluster+" "+fp.Hash] == url {
+		delete(idx.exact, cluster+" "+fp.Hash)
+	}
+	clusterBands := idx.bands[cluster]
+	for band := 0; band < bands; band++ {
+		key := bandKey(fp.SimHash, band)
+		entries := clusterBands[key]
+		for i, e := range entries {
+			if e.url == url {
+				entries = append(entries[:i], entries[i+1:]...)
+				break
+			}
+		}
+		if len(entries) == 0 {
+			delete(clusterBands, key)
+		} else {
+			clusterBands[key] = entries
+		}
+	}
+}
+
+// bandKey tags one 16-bit slice of a SimHash with its position
+func bandKey(simhash uint64, band int) uint64 {
+	slice := (simhash >> (band * bandBits)) & (1<<bandBits - 1)
+	return uint64(band)<<bandBits | slice
+}
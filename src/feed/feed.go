@@ -0,0 +1,178 @@
+package feed
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html/charset"
+
+	"webcrawler/types"
+	"webcrawler/utils"
+)
+
+// ErrNotFeed is returned by Parse for XML that is not RSS or Atom
+var ErrNotFeed = errors.New("not an RSS or Atom feed")
+
+// Content types a feed may be served as. Plain XML is parsed to find out
+var feedTypes = map[string]bool{
+	"application/rss+xml":  true,
+	"application/atom+xml": true,
+	"application/rdf+xml":  true,
+	"application/xml":      true,
+	"text/xml":             true,
+}
+
+// Date layouts seen in pubDate, dc:date, published and updated
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	time.RFC822Z,
+	time.RFC822,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+type rssItem struct {
+	Title   string `xml:"title"`
+	Link    string `xml:"link"`
+	GUID    string `xml:"guid"`
+	PubDate string `xml:"pubDate"`
+	Date    string `xml:"date"` // dc:date, in RSS 1.0 and some RSS 2.0
+}
+
+type atomEntry struct {
+	Title     string     `xml:"title"`
+	Links     []atomLink `xml:"link"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+// IsFeedType reports whether a Content-Type may hold a feed
+func IsFeedType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && feedTypes[mediaType]
+}
+
+// Discover returns the feeds a page advertises with
+// <link rel="alternate" type="application/rss+xml"> or Atom's equivalent
+func Discover(pageURL string, doc *goquery.Document) []string {
+	var feeds []string
+	doc.Find("link[rel][href][type]").Each(func(i int, s *goquery.Selection) {
+		if !strings.Contains(" "+strings.ToLower(s.AttrOr("rel", ""))+" ", " alternate ") {
+			return
+		}
+		mediaType := strings.ToLower(strings.TrimSpace(s.AttrOr("type", "")))
+		if mediaType != "application/rss+xml" && mediaType != "application/atom+xml" {
+			return
+		}
+		if feedURL := utils.ResolveURL(pageURL, s.AttrOr("href", "")); feedURL != "" {
+			feeds = append(feeds, feedURL)
+		}
+	})
+	return feeds
+}
+
+// Parse reads an RSS 2.0, RSS 1.0 or Atom feed and returns its items, with
+// links resolved against feedURL. Items without a link are skipped
+func Parse(r io.Reader, feedURL string) ([]types.FeedItem, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	decoder.CharsetReader = charset.NewReaderLabel
+
+	var items []types.FeedItem
+	root := true
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			if root {
+				return nil, ErrNotFeed
+			}
+			return items, nil
+		}
+		if err != nil {
+			return items, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if root {
+			switch start.Name.Local {
+			case "rss", "RDF", "feed":
+				root = false
+				continue
+			default:
+				return nil, ErrNotFeed
+			}
+		}
+
+		var item types.FeedItem
+		switch start.Name.Local {
+		case "item":
+			var entry rssItem
+			if err := decoder.DecodeElement(&entry, &start); err != nil {
+				return items, err
+			}
+			link := strings.TrimSpace(entry.Link)
+			if link == "" && strings.HasPrefix(strings.TrimSpace(entry.GUID), "http") {
+				// A permalink guid stands in for a missing link
+				link = strings.TrimSpace(entry.GUID)
+			}
+			item = types.FeedItem{URL: link, Title: strings.TrimSpace(entry.Title), Published: parseDate(entry.PubDate, entry.Date)}
+		case "entry":
+			var entry atomEntry
+			if err := decoder.DecodeElement(&entry, &start); err != nil {
+				return items, err
+			}
+			item = types.FeedItem{URL: entryLink(entry.Links), Title: strings.TrimSpace(entry.Title), Published: parseDate(entry.Published, entry.Updated)}
+		default:
+			continue
+		}
+
+		if item.URL == "" {
+			continue
+		}
+		if item.URL = utils.ResolveURL(feedURL, item.URL); item.URL != "" {
+			items = append(items, item)
+		}
+	}
+}
+
+// entryLink picks an Atom entry's alternate link; a link with no rel is one
+func entryLink(links []atomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return strings.TrimSpace(link.Href)
+		}
+	}
+	return ""
+}
+
+// parseDate returns the first of the values that parses, or the zero time
+func parseDate(values ...string) time.Time {
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,155 @@
+package filter
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// Names hosts files map for the machine itself, not entries of the list.
+// Names without a dot, like localhost, are dropped anyway
+var hostsFileNames = map[string]bool{
+	"localhost.localdomain": true,
+	"0.0.0.0":               true,
+}
+
+// HostTrie matches hosts against a set of domains, subdomains included.
+// Domains are stored label by label from the right, so a lookup costs one
+// step per label of the host however many domains are loaded
+type HostTrie struct {
+	root trieNode
+}
+
+type trieNode struct {
+	children map[string]*trieNode
+	terminal bool // A domain ends here
+}
+
+// Insert adds a domain
+func (t *HostTrie) Insert(domain string) {
+	node := &t.root
+	labels := strings.Split(domain, ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		if node.terminal {
+			return // A parent domain already covers it
+		}
+		child, ok := node.children[labels[i]]
+		if !ok {
+			if node.children == nil {
+				node.children = make(map[string]*trieNode)
+			}
+			child = &trieNode{}
+			node.children[labels[i]] = child
+		}
+		node = child
+	}
+	node.terminal = true
+	node.children = nil // Subdomains listed before it are now redundant
+}
+
+// Match reports whether host is one of the domains or a subdomain of one
+func (t *HostTrie) Match(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	node := &t.root
+	for host != "" {
+		label := host
+		if dot := strings.LastIndexByte(host, '.'); dot >= 0 {
+			label, host = host[dot+1:], host[:dot]
+		} else {
+			host = ""
+		}
+		if node = node.children[label]; node == nil {
+			return false
+		}
+		if node.terminal {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadBlocklist adds the domains of a blocklist file to the trie. Each line
+// may be in hosts-file format ("0.0.0.0 domain ..."), a plain domain, or a
+// simple adblock rule ("||domain^"); anything else, such as adblock rules
+// with options or exceptions, is skipped. Returns the number of domains read
+func (t *HostTrie) LoadBlocklist(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		for _, domain := range blocklistDomains(scanner.Text()) {
+			t.Insert(domain)
+			count++
+		}
+	}
+	return count, scanner.Err()
+}
+
+// blocklistDomains returns the domains one blocklist line lists
+func blocklistDomains(line string) []string {
+	line = strings.TrimSpace(line)
+	if line == "" || line[0] == '!' || line[0] == '[' {
+		return nil // Blank, an adblock comment or an adblock header
+	}
+
+	if strings.HasPrefix(line, "||") {
+		domain, ok := strings.CutSuffix(strings.TrimSuffix(line[2:], "|"), "^")
+		if !ok {
+			return nil
+		}
+		return validDomains(domain)
+	}
+
+	if hash := strings.IndexByte(line, '#'); hash >= 0 {
+		line = line[:hash]
+	}
+	fields := strings.Fields(line)
+	switch {
+	case len(fields) == 0:
+		return nil
+	case len(fields) == 1:
+		return validDomains(fields[0])
+	case isBlockAddress(fields[0]):
+		return validDomains(fields[1:]...)
+	}
+	return nil
+}
+
+// isBlockAddress reports whether a hosts-file address sinks the names on
+// its line
+func isBlockAddress(address string) bool {
+	switch address {
+	case "0.0.0.0", "127.0.0.1", "::", "::1", "0:0:0:0:0:0:0:0", "0:0:0:0:0:0:0:1":
+		return true
+	}
+	return false
+}
+
+// validDomains lowercases domains and drops anything that is not a
+// hostname, such as wildcards inside a name or the names hosts files use
+// for the machine itself
+func validDomains(candidates ...string) []string {
+	var domains []string
+	for _, domain := range candidates {
+		domain = strings.TrimSuffix(strings.TrimPrefix(strings.ToLower(domain), "*."), ".")
+		if domain == "" || hostsFileNames[domain] || !strings.Contains(domain, ".") {
+			continue
+		}
+		valid := true
+		for _, r := range domain {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '.' || r == '_') {
+				valid = false
+				break
+			}
+		}
+		if valid && !strings.Contains(domain, "..") && domain[0] != '.' {
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
@@ -0,0 +1,238 @@
+package filter
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// UnsupportedScheme names the built-in rule that rejects every URL that is
+// not http or https
+const UnsupportedScheme = "unsupported-scheme"
+
+// Rule allows or denies the URLs that meet all of its conditions
+type Rule struct {
+	Name       string
+	Allow      bool
+	conditions []condition
+}
+
+// condition is one kind:value test of a rule
+type condition func(u *url.URL, raw string) bool
+
+// Filter is an ordered list of rules; the first rule a URL matches decides
+type Filter struct {
+	rules []Rule
+}
+
+// Add appends a rule, so it is checked after every rule added before it
+func (f *Filter) Add(rule Rule) {
+	f.rules = append(f.rules, rule)
+}
+
+// Len returns the number of rules
+func (f *Filter) Len() int {
+	return len(f.rules)
+}
+
+// Check decides whether a URL may be crawled. Only http and https are, and
+// a URL no rule matches is allowed. Returns the name of the deciding rule,
+// or "" if none matched
+func (f *Filter) Check(rawURL string) (bool, string) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false, "unparsable"
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false, UnsupportedScheme
+	}
+
+	for _, rule := range f.rules {
+		if rule.matches(u, rawURL) {
+			return rule.Allow, rule.Name
+		}
+	}
+	return true, ""
+}
+
+func (r Rule) matches(u *url.URL, raw string) bool {
+	for _, cond := range r.conditions {
+		if !cond(u, raw) {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseRule reads a "name kind:value..." line from an Allow: or Deny:
+// section. A URL must meet every condition, and any of the comma-separated
+// values of one. Kinds are scheme, host, suffix (host or subdomain), path
+// (glob, * matches anything), regex (over the whole URL), ext and param
+func ParseRule(line string, allow bool) (Rule, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return Rule{}, fmt.Errorf("invalid rule %q: want \"name kind:value...\"", line)
+	}
+
+	rule := Rule{Name: fields[0], Allow: allow}
+	for _, field := range fields[1:] {
+		kind, value, ok := strings.Cut(field, ":")
+		if !ok || value == "" {
+			return Rule{}, fmt.Errorf("invalid condition %q in rule %q", field, rule.Name)
+		}
+		cond, err := parseCondition(kind, value)
+		if err != nil {
+			return Rule{}, fmt.Errorf("rule %q: %w", rule.Name, err)
+		}
+		rule.conditions = append(rule.conditions, cond)
+	}
+	return rule, nil
+}
+
+// HostRule builds a rule matching a domain and its subdomains, optionally
+// only under a path prefix. Blacklist entries such as "example.com/ads" or
+// "https://example.com" become these; the scheme of an entry is ignored
+func HostRule(name, entry string, allow bool) (Rule, error) {
+	raw := strings.TrimPrefix(entry, "*.") // Subdomains are covered anyway
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Hostname() == "" || strings.ContainsAny(parsed.Host, "*") {
+		return Rule{}, fmt.Errorf("invalid host entry %q", entry)
+	}
+
+	rule := Rule{Name: name, Allow: allow}
+	rule.conditions = append(rule.conditions, suffixCondition([]string{parsed.Hostname()}))
+	if prefix := strings.TrimSuffix(parsed.Path, "/"); prefix != "" {
+		// A prefix ends on a segment boundary, so /ads does not cover /adsense
+		rule.conditions = append(rule.conditions, func(u *url.URL, raw string) bool {
+			path := pathOf(u)
+			return path == prefix || strings.HasPrefix(path, prefix+"/")
+		})
+	}
+	return rule, nil
+}
+
+// MatcherRule wraps a custom host matcher, such as a loaded blocklist, as a
+// rule
+func MatcherRule(name string, allow bool, matchHost func(host string) bool) Rule {
+	return Rule{Name: name, Allow: allow, conditions: []condition{
+		func(u *url.URL, raw string) bool { return matchHost(strings.ToLower(u.Hostname())) },
+	}}
+}
+
+func parseCondition(kind, value string) (condition, error) {
+	// A regex may contain commas, so it is never split
+	if kind == "regex" {
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, err
+		}
+		return func(u *url.URL, raw string) bool { return re.MatchString(raw) }, nil
+	}
+
+	values := strings.Split(value, ",")
+	switch kind {
+	case "scheme":
+		set := lowerSet(values)
+		return func(u *url.URL, raw string) bool { return set[strings.ToLower(u.Scheme)] }, nil
+	case "host":
+		set := lowerSet(values)
+		return func(u *url.URL, raw string) bool { return set[strings.ToLower(u.Hostname())] }, nil
+	case "suffix":
+		return suffixCondition(values), nil
+	case "path":
+		return func(u *url.URL, raw string) bool {
+			p := pathOf(u)
+			for _, glob := range values {
+				if globMatch(glob, p) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case "ext":
+		set := lowerSet(values)
+		return func(u *url.URL, raw string) bool {
+			ext := strings.TrimPrefix(path.Ext(pathOf(u)), ".")
+			return ext != "" && set[strings.ToLower(ext)]
+		}, nil
+	case "param":
+		return func(u *url.URL, raw string) bool {
+			for key := range u.Query() {
+				for _, name := range values {
+					if key == name || strings.HasSuffix(name, "*") && strings.HasPrefix(key, strings.TrimSuffix(name, "*")) {
+						return true
+					}
+				}
+			}
+			return false
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown condition kind %q", kind)
+}
+
+func suffixCondition(domains []string) condition {
+	domains = append([]string(nil), domains...)
+	for i := range domains {
+		domains[i] = strings.TrimPrefix(strings.ToLower(domains[i]), ".")
+	}
+	return func(u *url.URL, raw string) bool {
+		host := strings.ToLower(u.Hostname())
+		for _, domain := range domains {
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func lowerSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[strings.ToLower(strings.TrimPrefix(value, "."))] = true
+	}
+	return set
+}
+
+func pathOf(u *url.URL) string {
+	if u.Path == "" {
+		return "/"
+	}
+	return u.Path
+}
+
+// globMatch matches a path against a glob where * matches any run of
+// characters, slashes included, and ? matches one
+func globMatch(glob, s string) bool {
+	for len(glob) > 0 {
+		switch glob[0] {
+		case '*':
+			glob = strings.TrimLeft(glob, "*")
+			if glob == "" {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if globMatch(glob, s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if s == "" {
+				return false
+			}
+			glob, s = glob[1:], s[1:]
+		default:
+			if s == "" || glob[0] != s[0] {
+				return false
+			}
+			glob, s = glob[1:], s[1:]
+		}
+	}
+	return s == ""
+}
@@ -0,0 +1,332 @@
+package frontier
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"webcrawler/types"
+)
+
+// Item is a queued URL with what the crawl needs to know when it gets to it
+type Item struct {
+	URL      string       `msgpack:"url"`
+	Depth    int          `msgpack:"depth"`
+	Origin   types.Origin `msgpack:"origin"`   // Seed and off-site hops, for scoping its links
+	Attempts int          `msgpack:"attempts"` // Retries used so far
+}
+
+// State is where a frontier stood at a checkpoint: the segment files still
+// to read, and how far into the first one reading had got
+type State struct {
+	Segments []string `msgpack:"segments"` // File names, oldest first
+	Skip     int      `msgpack:"skip"`     // Items of the first segment already read
+}
+
+// Dir returns the directory the frontier of a crawl keeps its files in
+func Dir(stateDir string) string {
+	return filepath.Join(stateDir, "frontier")
+}
+
+// Frontier is a first-in first-out queue of URLs kept in segment files on
+// disk, so only the segment being written and the one being read take
+// memory. It is not safe for concurrent use
+type Frontier struct {
+	dir         string
+	segmentSize int      // URLs per segment file
+	segments    []string // Finished segment files, oldest first
+	writer      *os.File // Segment being appended to
+	buffer      *bufio.Writer
+	written     int // URLs in the segment being appended to
+	reader      *os.File
+	scanner     *bufio.Scanner
+	readPath    string
+	read        int             // Lines read from the segment being read
+	consumed    []string        // Segments read to the end since the last checkpoint
+	committing  []string        // Segments to remove once the checkpoint is saved
+	drop        map[string]bool // URLs to skip when read back, set by Resume
+	next        int             // Number of the next segment file
+	size        int
+}
+
+// New opens an empty frontier in dir. Segments left by an earlier run are
+// removed; Resume is for continuing from a checkpoint
+func New(dir string, segmentSize int) (*Frontier, error) {
+	return Resume(dir, segmentSize, State{}, nil)
+}
+
+// Resume reopens a frontier at a checkpointed state. Segments the state
+// does not list are removed. Items for which drop returns true, such as
+// pages saved after the checkpoint, are skipped when read back
+func Resume(dir string, segmentSize int, state State, drop func(Item) bool) (*Frontier, error) {
+	if segmentSize < 1 {
+		segmentSize = 1
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	f := &Frontier{dir: dir, segmentSize: segmentSize, drop: make(map[string]bool)}
+	keep := make(map[string]bool, len(state.Segments))
+	for _, name := range state.Segments {
+		keep[name] = true
+		var number int
+		if _, err := fmt.Sscanf(name, "segment-%08d.txt", &number); err == nil && number >= f.next {
+			f.next = number + 1
+		}
+	}
+
+	old, err := filepath.Glob(filepath.Join(dir, "segment-*.txt"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range old {
+		if keep[filepath.Base(path)] {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, name := range state.Segments {
+		skip := 0
+		if i == 0 {
+			skip = state.Skip
+		}
+		path := filepath.Join(dir, name)
+		count, err := f.count(path, skip, drop)
+		if err != nil {
+			return nil, err
+		}
+		f.segments = append(f.segments, path)
+		f.size += count
+	}
+
+	// Reading picks up where the checkpoint left the first segment
+	if len(f.segments) > 0 && state.Skip > 0 {
+		if err := f.open(); err != nil {
+			return nil, err
+		}
+		for f.read < state.Skip && f.scanner.Scan() {
+			f.read++
+		}
+		if err := f.scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// count returns the items of a segment after the first skip, leaving out
+// those drop rejects and remembering them so Pop skips them too
+func (f *Frontier) count(path string, skip int, drop func(Item) bool) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	count := 0
+	for line := 0; scanner.Scan(); line++ {
+		if line < skip {
+			continue
+		}
+		if drop != nil {
+			item, err := decode(scanner.Text())
+			if err != nil {
+				return 0, fmt.Errorf("%s: %w", path, err)
+			}
+			if drop(item) {
+				f.drop[item.URL] = true
+				continue
+			}
+		}
+		count++
+	}
+	return count, scanner.Err()
+}
+
+// Push appends an item
+func (f *Frontier) Push(item Item) error {
+	line, err := encode(item)
+	if err != nil {
+		return err
+	}
+	if f.writer == nil {
+		path := filepath.Join(f.dir, fmt.Sprintf("segment-%08d.txt", f.next))
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		f.next++
+		f.writer, f.buffer, f.written = file, bufio.NewWriter(file), 0
+	}
+
+	if _, err := f.buffer.WriteString(line + "\n"); err != nil {
+		return err
+	}
+	f.written++
+	f.size++
+	if f.written >= f.segmentSize {
+		return f.rotate()
+	}
+	return nil
+}
+
+// Pop removes and returns the oldest item, or false if the frontier is empty
+func (f *Frontier) Pop() (Item, bool, error) {
+	for f.size > 0 {
+		if f.scanner == nil {
+			if len(f.segments) == 0 {
+				// Only the segment being written is left; finish it early
+				if err := f.rotate(); err != nil {
+					return Item{}, false, err
+				}
+			}
+			if len(f.segments) == 0 {
+				// URLs were counted but never made it to disk
+				f.size = 0
+				break
+			}
+			if err := f.open(); err != nil {
+				return Item{}, false, err
+			}
+		}
+
+		if f.scanner.Scan() {
+			f.read++
+			item, err := decode(f.scanner.Text())
+			if err != nil {
+				return Item{}, false, fmt.Errorf("%s: %w", f.readPath, err)
+			}
+			if f.drop[item.URL] {
+				delete(f.drop, item.URL)
+				continue
+			}
+			f.size--
+			return item, true, nil
+		}
+		err := f.scanner.Err()
+		f.reader.Close()
+		f.reader, f.scanner = nil, nil
+		// The last checkpoint may still list it; Commit removes it
+		f.consumed = append(f.consumed, f.readPath)
+		if err != nil {
+			return Item{}, false, err
+		}
+	}
+	return Item{}, false, nil
+}
+
+// Len returns the number of URLs in the frontier
+func (f *Frontier) Len() int {
+	return f.size
+}
+
+// Checkpoint makes everything pushed so far durable and returns the state
+// to resume from. Segments read since the last checkpoint are removed by
+// Commit, once the new checkpoint is saved
+func (f *Frontier) Checkpoint() (State, error) {
+	if err := f.rotate(); err != nil {
+		return State{}, err
+	}
+
+	var state State
+	if f.reader != nil {
+		state.Segments = append(state.Segments, filepath.Base(f.readPath))
+		state.Skip = f.read
+	}
+	for _, path := range f.segments {
+		state.Segments = append(state.Segments, filepath.Base(path))
+	}
+	f.committing = append(f.committing, f.consumed...)
+	f.consumed = nil
+	return state, nil
+}
+
+// Commit removes the segments the last Checkpoint no longer lists
+func (f *Frontier) Commit() {
+	for _, path := range f.committing {
+		os.Remove(path)
+	}
+	f.committing = nil
+}
+
+// Close releases the open segment files. The segments stay on disk for a
+// checkpoint to resume from
+func (f *Frontier) Close() {
+	if f.writer != nil {
+		f.buffer.Flush()
+		f.writer.Close()
+		f.writer = nil
+	}
+	if f.reader != nil {
+		f.reader.Close()
+		f.reader, f.scanner = nil, nil
+	}
+}
+
+// open starts reading the oldest finished segment
+func (f *Frontier) open() error {
+	file, err := os.Open(f.segments[0])
+	if err != nil {
+		return err
+	}
+	f.reader, f.scanner, f.readPath, f.read = file, bufio.NewScanner(file), f.segments[0], 0
+	f.scanner.Buffer(nil, 1<<20)
+	f.segments = f.segments[1:]
+	return nil
+}
+
+// rotate finishes the segment being written so it can be read
+func (f *Frontier) rotate() error {
+	if f.writer == nil {
+		return nil
+	}
+	err := f.buffer.Flush()
+	if syncErr := f.writer.Sync(); err == nil {
+		err = syncErr
+	}
+	if closeErr := f.writer.Close(); err == nil {
+		err = closeErr
+	}
+	f.segments = append(f.segments, f.writer.Name())
+	f.writer, f.buffer = nil, nil
+	return err
+}
+
+// encode writes an item as a tab-separated line: depth, attempts, hops,
+// seed and URL, which goes last since it is the longest
+func encode(item Item) (string, error) {
+	if strings.ContainsAny(item.URL+item.Origin.Seed, "\t\r\n") {
+		return "", fmt.Errorf("frontier: URL contains a tab or line break: %q", item.URL)
+	}
+	return strconv.Itoa(item.Depth) + "\t" + strconv.Itoa(item.Attempts) + "\t" +
+		strconv.Itoa(item.Origin.Hops) + "\t" + item.Origin.Seed + "\t" + item.URL, nil
+}
+
+func decode(line string) (Item, error) {
+	fields := strings.SplitN(line, "\t", 5)
+	if len(fields) != 5 {
+		return Item{}, errors.New("malformed frontier line")
+	}
+	depth, err1 := strconv.Atoi(fields[0])
+	attempts, err2 := strconv.Atoi(fields[1])
+	hops, err3 := strconv.Atoi(fields[2])
+	if err := errors.Join(err1, err2, err3); err != nil {
+		return Item{}, err
+	}
+	return Item{
+		URL:      fields[4],
+		Depth:    depth,
+		Origin:   types.Origin{Seed: fields[3], Hops: hops},
+		Attempts: attempts,
+	}, nil
+}
@@ -0,0 +1,192 @@
+package frontier
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"webcrawler/types"
+)
+
+// Hints are the sitemap entries and feed publish dates known for URLs not
+// crawled yet. Every change is appended to a file as a line, so a
+// checkpoint only records how long the file was. It is not safe for
+// concurrent use
+type Hints struct {
+	sitemaps  map[string]types.SitemapEntry
+	published map[string]time.Time
+	file      *os.File // nil for hints kept only in memory
+	buffer    *bufio.Writer
+	size      int64 // Bytes written to the file
+	err       error // First write error, reported by Sync
+}
+
+// NewHints returns empty hints kept only in memory
+func NewHints() *Hints {
+	return &Hints{sitemaps: make(map[string]types.SitemapEntry), published: make(map[string]time.Time)}
+}
+
+// OpenHints opens the hints stored at path with their first size bytes,
+// the length a checkpoint recorded, and drops anything written after. A
+// size of 0 starts with no hints
+func OpenHints(path string, size int64) (*Hints, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	h := NewHints()
+	scanner := bufio.NewScanner(io.LimitReader(file, size))
+	scanner.Buffer(nil, 1<<20)
+	read := int64(0)
+	for scanner.Scan() {
+		read += int64(len(scanner.Bytes())) + 1
+		if err := h.apply(scanner.Text()); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if read != size {
+		file.Close()
+		return nil, fmt.Errorf("%s holds %d of the %d bytes checkpointed", path, read, size)
+	}
+
+	if err := file.Truncate(size); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if _, err := file.Seek(size, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	h.file, h.buffer, h.size = file, bufio.NewWriter(file), size
+	return h, nil
+}
+
+// Sitemap returns the sitemap entry for a URL, if it has one
+func (h *Hints) Sitemap(targetURL string) (types.SitemapEntry, bool) {
+	entry, ok := h.sitemaps[targetURL]
+	return entry, ok
+}
+
+// Published returns the publish date a feed gave for a URL, zero if none
+func (h *Hints) Published(targetURL string) time.Time {
+	return h.published[targetURL]
+}
+
+// SetSitemap keeps a sitemap entry for its URL
+func (h *Hints) SetSitemap(entry types.SitemapEntry) {
+	entry.ChangeFreq = strings.Join(strings.Fields(entry.ChangeFreq), " ")
+	h.write(strings.Join([]string{"s", formatTime(entry.LastMod), entry.ChangeFreq,
+		strconv.FormatFloat(entry.Priority, 'g', -1, 64), entry.URL}, "\t"))
+	h.sitemaps[entry.URL] = entry
+}
+
+// SetPublished keeps the publish date a feed gave for a URL
+func (h *Hints) SetPublished(targetURL string, published time.Time) {
+	h.write("p\t" + formatTime(published) + "\t" + targetURL)
+	h.published[targetURL] = published
+}
+
+// Forget drops the hints for a URL once it has been crawled
+func (h *Hints) Forget(targetURL string) {
+	_, sitemap := h.sitemaps[targetURL]
+	_, published := h.published[targetURL]
+	if !sitemap && !published {
+		return
+	}
+	h.write("d\t" + targetURL)
+	delete(h.sitemaps, targetURL)
+	delete(h.published, targetURL)
+}
+
+// Sync writes the hints to disk and returns the length of the file, to be
+// passed to OpenHints when resuming
+func (h *Hints) Sync() (int64, error) {
+	if h.file == nil {
+		return 0, errors.New("hints are not backed by a file")
+	}
+	if h.err != nil {
+		return 0, h.err
+	}
+	if err := h.buffer.Flush(); err != nil {
+		return 0, err
+	}
+	if err := h.file.Sync(); err != nil {
+		return 0, err
+	}
+	return h.size, nil
+}
+
+// Close flushes and closes the file behind the hints
+func (h *Hints) Close() error {
+	if h.file == nil {
+		return nil
+	}
+	err := h.buffer.Flush()
+	if closeErr := h.file.Close(); err == nil {
+		err = closeErr
+	}
+	h.file, h.buffer = nil, nil
+	return err
+}
+
+// write appends a change to the file. URLs with a tab or line break are
+// never queued, so they cannot break a line
+func (h *Hints) write(line string) {
+	if h.buffer == nil || h.err != nil {
+		return
+	}
+	_, h.err = h.buffer.WriteString(line + "\n")
+	h.size += int64(len(line)) + 1
+}
+
+// apply replays a line of the file: s for a sitemap entry, p for a publish
+// date, d for a URL crawled. The URL goes last, as in segment files
+func (h *Hints) apply(line string) error {
+	fields := strings.Split(line, "\t")
+	switch {
+	case fields[0] == "s" && len(fields) == 5:
+		lastMod, err1 := parseTime(fields[1])
+		priority, err2 := strconv.ParseFloat(fields[3], 64)
+		if err := errors.Join(err1, err2); err != nil {
+			return err
+		}
+		h.sitemaps[fields[4]] = types.SitemapEntry{URL: fields[4], LastMod: lastMod, ChangeFreq: fields[2], Priority: priority}
+	case fields[0] == "p" && len(fields) == 3:
+		published, err := parseTime(fields[1])
+		if err != nil {
+			return err
+		}
+		h.published[fields[2]] = published
+	case fields[0] == "d" && len(fields) == 2:
+		delete(h.sitemaps, fields[1])
+		delete(h.published, fields[1])
+	default:
+		return errors.New("malformed hints line")
+	}
+	return nil
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339Nano, value)
+}
@@ -0,0 +1,173 @@
+package frontier
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+)
+
+// The Bloom filter takes 16 bits per URL it is sized for and probes 11 of
+// them, so once full about one new URL in 2000 is taken for seen
+const (
+	seenBitsPerURL = 16
+	seenProbes     = 11
+)
+
+// Seen is the set of URLs a crawl has queued. It appends a 64-bit hash of
+// each new URL to a file, so a checkpoint only records how many there were,
+// and keeps only a Bloom filter of them in memory. A false positive makes a
+// new URL look seen, so it is skipped; the filter is sized up front so that
+// stays rare. It is not safe for concurrent use
+type Seen struct {
+	bits   []uint64
+	count  int64
+	file   *os.File // nil for a set kept only in memory
+	buffer *bufio.Writer
+	err    error // First write error, reported by Sync
+}
+
+// NewSeen returns an empty set kept only in memory, sized for size URLs
+func NewSeen(size int) *Seen {
+	return &Seen{bits: newFilter(int64(size))}
+}
+
+// OpenSeen opens the set stored at path with the first count hashes of it,
+// the number a checkpoint recorded, and drops any written after. A count
+// of 0 starts an empty set. The filter is sized for size URLs, or count if
+// more are stored already
+func OpenSeen(path string, count int64, size int) (*Seen, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Seen{bits: newFilter(max(int64(size), count)), file: file}
+	reader := bufio.NewReader(file)
+	var entry [8]byte
+	for i := int64(0); i < count; i++ {
+		if _, err := io.ReadFull(reader, entry[:]); err != nil {
+			file.Close()
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil, fmt.Errorf("%s holds %d of the %d hashes checkpointed", path, i, count)
+			}
+			return nil, err
+		}
+		s.set(binary.LittleEndian.Uint64(entry[:]))
+	}
+	s.count = count
+
+	if err := file.Truncate(count * 8); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if _, err := file.Seek(count*8, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	s.buffer = bufio.NewWriter(file)
+	return s, nil
+}
+
+// Add adds a URL and returns false if it was already in the set
+func (s *Seen) Add(targetURL string) bool {
+	hash := hashURL(targetURL)
+	if s.test(hash) {
+		return false
+	}
+	s.set(hash)
+	s.count++
+
+	if s.buffer != nil && s.err == nil {
+		var entry [8]byte
+		binary.LittleEndian.PutUint64(entry[:], hash)
+		_, s.err = s.buffer.Write(entry[:])
+	}
+	return true
+}
+
+// Has reports whether a URL is in the set
+func (s *Seen) Has(targetURL string) bool {
+	return s.test(hashURL(targetURL))
+}
+
+// Len returns the number of URLs in the set
+func (s *Seen) Len() int {
+	return int(s.count)
+}
+
+// Sync writes the set to disk and returns the number of hashes stored, to
+// be passed to OpenSeen when resuming
+func (s *Seen) Sync() (int64, error) {
+	if s.file == nil {
+		return 0, errors.New("seen set is not backed by a file")
+	}
+	if s.err != nil {
+		return 0, s.err
+	}
+	if err := s.buffer.Flush(); err != nil {
+		return 0, err
+	}
+	if err := s.file.Sync(); err != nil {
+		return 0, err
+	}
+	return s.count, nil
+}
+
+// Close flushes and closes the file behind the set
+func (s *Seen) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.buffer.Flush()
+	if closeErr := s.file.Close(); err == nil {
+		err = closeErr
+	}
+	s.file, s.buffer = nil, nil
+	return err
+}
+
+func newFilter(size int64) []uint64 {
+	return make([]uint64, max(size, 1024)*seenBitsPerURL/64)
+}
+
+// set and test probe the filter with double hashing: the URL hash and a
+// remix of it give the step between probes
+func (s *Seen) set(hash uint64) {
+	bits := uint64(len(s.bits) * 64)
+	step := remix(hash) | 1
+	for i := uint64(0); i < seenProbes; i++ {
+		bit := (hash + i*step) % bits
+		s.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+func (s *Seen) test(hash uint64) bool {
+	bits := uint64(len(s.bits) * 64)
+	step := remix(hash) | 1
+	for i := uint64(0); i < seenProbes; i++ {
+		bit := (hash + i*step) % bits
+		if s.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// remix is the splitmix64 finalizer
+func remix(hash uint64) uint64 {
+	hash ^= hash >> 30
+	hash *= 0xbf58476d1ce4e5b9
+	hash ^= hash >> 27
+	hash *= 0x94d049bb133111eb
+	return hash ^ hash>>31
+}
+
+func hashURL(targetURL string) uint64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(targetURL))
+	return hash.Sum64()
+}
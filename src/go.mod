@@ -5,6 +5,7 @@ go 1.23.5
 require (
 	github.com/PuerkitoBio/goquery v1.10.1
 	github.com/temoto/robotstxt v1.1.2
+	golang.org/x/text v0.21.0
 	golang.org/x/time v0.10.0
 )
 
@@ -13,5 +14,5 @@ require github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
 require (
 	github.com/andybalholm/cascadia v1.3.3 // indirect
 	github.com/vmihailenco/msgpack/v5 v5.4.1
-	golang.org/x/net v0.33.0 // indirect
+	golang.org/x/net v0.33.0
 )
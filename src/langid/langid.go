@@ -0,0 +1,211 @@
+package langid
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+const (
+	maxGram   = 3    // Longest character n-gram
+	maxRunes  = 4000 // Text beyond this adds time, not accuracy
+	minLetter = 10   // Fewer letters than this are not worth a guess
+	smoothing = 0.5  // Added to every n-gram count so unseen ones are not impossible
+)
+
+// Languages with a script of their own need no n-grams
+var scriptLanguages = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Thai, "th"},
+	{unicode.Greek, "el"},
+	{unicode.Hebrew, "he"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Bengali, "bn"},
+	{unicode.Tamil, "ta"},
+	{unicode.Georgian, "ka"},
+	{unicode.Armenian, "hy"},
+}
+
+// model is the n-gram log probabilities of one language
+type model struct {
+	lang    string
+	logProb map[string]float64
+	unseen  float64 // Log probability of an n-gram missing from the sample
+}
+
+// Models grouped by the script their languages are written in
+var models = map[string][]model{}
+
+func init() {
+	scripts := map[string]string{"ru": "cyrillic", "uk": "cyrillic", "ar": "arabic", "fa": "arabic"}
+
+	// One vocabulary across all samples, so smoothing treats every
+	// language alike
+	counts := make(map[string]map[string]int)
+	vocabulary := make(map[string]bool)
+	for lang, sample := range samples {
+		counts[lang] = make(map[string]int)
+		for _, gram := range ngrams(sample) {
+			counts[lang][gram]++
+			vocabulary[gram] = true
+		}
+	}
+
+	for lang, grams := range counts {
+		total := 0
+		for _, count := range grams {
+			total += count
+		}
+		denominator := float64(total) + smoothing*float64(len(vocabulary)+1)
+
+		m := model{
+			lang:    lang,
+			logProb: make(map[string]float64, len(grams)),
+			unseen:  math.Log(smoothing / denominator),
+		}
+		for gram, count := range grams {
+			m.logProb[gram] = math.Log((float64(count) + smoothing) / denominator)
+		}
+
+		script := scripts[lang]
+		if script == "" {
+			script = "latin"
+		}
+		models[script] = append(models[script], m)
+	}
+}
+
+// Detect guesses the language of a text, returning an ISO 639-1 code and a
+// confidence from 0 to 1. Short or letterless text gives "" and 0
+func Detect(text string) (string, float64) {
+	runes := []rune(text)
+	if len(runes) > maxRunes {
+		runes = runes[:maxRunes]
+	}
+	text = string(runes)
+
+	script, share := dominantScript(text)
+	if script == "" {
+		return "", 0
+	}
+	for _, s := range scriptLanguages {
+		if script == s.lang {
+			return s.lang, share
+		}
+	}
+	if script == "zh" || script == "ja" {
+		return script, share
+	}
+
+	candidates := models[script]
+	grams := ngrams(text)
+	if len(candidates) == 0 || len(grams) == 0 {
+		return "", 0
+	}
+
+	// Average log likelihood per n-gram, so long texts are not overconfident
+	scores := make([]float64, len(candidates))
+	best := 0
+	for i, m := range candidates {
+		for _, gram := range grams {
+			if p, ok := m.logProb[gram]; ok {
+				scores[i] += p
+			} else {
+				scores[i] += m.unseen
+			}
+		}
+		scores[i] /= float64(len(grams))
+		if scores[i] > scores[best] {
+			best = i
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0].lang, share
+	}
+
+	// Posterior of the winner. n-grams of the same words are far from
+	// independent, so evidence grows with the square root of their number
+	weight := math.Sqrt(float64(len(grams)))
+	sum := 0.0
+	for _, score := range scores {
+		sum += math.Exp(weight * (score - scores[best]))
+	}
+	return candidates[best].lang, share / sum
+}
+
+// dominantScript names the script most letters are written in, with the
+// share of letters in it. Scripts used by one language are named by that
+// language; Han with any kana is Japanese
+func dominantScript(text string) (string, float64) {
+	counts := make(map[string]int)
+	letters := 0
+	kana := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			counts["latin"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			counts["cyrillic"]++
+		case unicode.Is(unicode.Arabic, r):
+			counts["arabic"]++
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			counts["cjk"]++
+			kana++
+		case unicode.Is(unicode.Han, r):
+			counts["cjk"]++
+		default:
+			for _, s := range scriptLanguages {
+				if unicode.Is(s.table, r) {
+					counts[s.lang]++
+					break
+				}
+			}
+		}
+	}
+	if letters < minLetter {
+		return "", 0
+	}
+
+	script, most := "", 0
+	for name, count := range counts {
+		if count > most || (count == most && name < script) {
+			script, most = name, count
+		}
+	}
+	if script == "cjk" {
+		// Japanese mixes kana into nearly every sentence
+		script = "zh"
+		if float64(kana) > 0.1*float64(most) {
+			script = "ja"
+		}
+	}
+	return script, float64(most) / float64(letters)
+}
+
+// ngrams splits text into lowercase words and returns their character
+// n-grams, with a space marking each word's start and end
+func ngrams(text string) []string {
+	var grams []string
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.Is(unicode.Mn, r)
+	})
+	for _, word := range words {
+		runes := []rune(" " + word + " ")
+		for n := 1; n <= maxGram; n++ {
+			for i := 0; i+n <= len(runes); i++ {
+				if n == 1 && runes[i] == ' ' {
+					continue
+				}
+				grams = append(grams, string(runes[i:i+n]))
+			}
+		}
+	}
+	return grams
+}
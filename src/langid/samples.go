@@ -0,0 +1,120 @@
+package langid
+
+// Training text for the n-gram models of languages that share a script.
+// Each sample mixes the Universal Declaration of Human Rights with the kind
+// of everyday prose found on news sites, shops and forums
+var samples = map[string]string{
+	"en": `All human beings are born free and equal in dignity and rights. They are endowed with reason and conscience and should act towards one another in a spirit of brotherhood.
+Everyone is entitled to all the rights and freedoms set forth in this Declaration, without distinction of any kind, such as race, colour, sex, language, religion, political or other opinion.
+The weather will be sunny this weekend with temperatures rising in the afternoon. Click here to read more about our new products and sign up for the newsletter.
+We use cookies to improve your experience on our website. If you have any questions, please contact our support team, which is available every day of the week.
+The government announced on Tuesday that the new law would come into force next year, after months of debate between the parties. What do you think about it?`,
+
+	"es": `Todos los seres humanos nacen libres e iguales en dignidad y derechos y, dotados como están de razón y conciencia, deben comportarse fraternalmente los unos con los otros.
+Toda persona tiene todos los derechos y libertades proclamados en esta Declaración, sin distinción alguna de raza, color, sexo, idioma, religión, opinión política o de cualquier otra índole.
+El tiempo será soleado este fin de semana y las temperaturas subirán por la tarde. Haga clic aquí para leer más sobre nuestros nuevos productos y suscríbase al boletín.
+Utilizamos cookies para mejorar su experiencia en nuestro sitio web. Si tiene alguna pregunta, póngase en contacto con nuestro equipo de atención, que está disponible todos los días.
+El gobierno anunció el martes que la nueva ley entrará en vigor el próximo año, después de meses de debate entre los partidos. ¿Qué opina usted?`,
+
+	"fr": `Tous les êtres humains naissent libres et égaux en dignité et en droits. Ils sont doués de raison et de conscience et doivent agir les uns envers les autres dans un esprit de fraternité.
+Chacun peut se prévaloir de tous les droits et de toutes les libertés proclamés dans la présente Déclaration, sans distinction aucune, notamment de race, de couleur, de sexe, de langue, de religion ou d'opinion politique.
+Le temps sera ensoleillé ce week-end et les températures augmenteront dans l'après-midi. Cliquez ici pour en savoir plus sur nos nouveaux produits et abonnez-vous à la lettre d'information.
+Nous utilisons des cookies pour améliorer votre expérience sur notre site. Si vous avez des questions, veuillez contacter notre équipe, qui est disponible tous les jours de la semaine.
+Le gouvernement a annoncé mardi que la nouvelle loi entrerait en vigueur l'année prochaine, après des mois de débat entre les partis. Qu'en pensez-vous ?`,
+
+	"de": `Alle Menschen sind frei und gleich an Würde und Rechten geboren. Sie sind mit Vernunft und Gewissen begabt und sollen einander im Geist der Brüderlichkeit begegnen.
+Jeder hat Anspruch auf die in dieser Erklärung verkündeten Rechte und Freiheiten ohne irgendeinen Unterschied, etwa nach Rasse, Hautfarbe, Geschlecht, Sprache, Religion, politischer oder sonstiger Überzeugung.
+Das Wetter wird am Wochenende sonnig und die Temperaturen steigen am Nachmittag. Klicken Sie hier, um mehr über unsere neuen Produkte zu erfahren, und melden Sie sich für den Newsletter an.
+Wir verwenden Cookies, um Ihre Erfahrung auf unserer Webseite zu verbessern. Wenn Sie Fragen haben, wenden Sie sich bitte an unser Team, das jeden Tag der Woche für Sie da ist.
+Die Regierung hat am Dienstag angekündigt, dass das neue Gesetz im nächsten Jahr in Kraft treten wird, nach monatelangen Debatten zwischen den Parteien. Was halten Sie davon?`,
+
+	"it": `Tutti gli esseri umani nascono liberi ed eguali in dignità e diritti. Essi sono dotati di ragione e di coscienza e devono agire gli uni verso gli altri in spirito di fratellanza.
+Ad ogni individuo spettano tutti i diritti e tutte le libertà enunciati nella presente Dichiarazione, senza distinzione alcuna, per ragioni di razza, di colore, di sesso, di lingua, di religione, di opinione politica o di altro genere.
+Il tempo sarà soleggiato questo fine settimana e le temperature saliranno nel pomeriggio. Clicca qui per saperne di più sui nostri nuovi prodotti e iscriviti alla newsletter.
+Utilizziamo i cookie per migliorare la tua esperienza sul nostro sito. Se hai domande, contatta il nostro servizio clienti, che è disponibile tutti i giorni della settimana.
+Il governo ha annunciato martedì che la nuova legge entrerà in vigore il prossimo anno, dopo mesi di dibattito tra i partiti. Che cosa ne pensi?`,
+
+	"pt": `Todos os seres humanos nascem livres e iguais em dignidade e em direitos. Dotados de razão e de consciência, devem agir uns para com os outros em espírito de fraternidade.
+Todos os seres humanos podem invocar os direitos e as liberdades proclamados na presente Declaração, sem distinção alguma, nomeadamente de raça, de cor, de sexo, de língua, de religião, de opinião política ou outra.
+O tempo estará ensolarado neste fim de semana e as temperaturas vão subir durante a tarde. Clique aqui para saber mais sobre os nossos novos produtos e inscreva-se na newsletter.
+Usamos cookies para melhorar a sua experiência no nosso site. Se tiver alguma dúvida, entre em contato com a nossa equipe, que está disponível todos os dias da semana.
+O governo anunciou na terça-feira que a nova lei entrará em vigor no próximo ano, depois de meses de debate entre os partidos. O que você acha disso?`,
+
+	"nl": `Alle mensen worden vrij en gelijk in waardigheid en rechten geboren. Zij zijn begiftigd met verstand en geweten, en behoren zich jegens elkander in een geest van broederschap te gedragen.
+Een ieder heeft aanspraak op alle rechten en vrijheden, uiteengezet in deze Verklaring, zonder enig onderscheid van welke aard ook, zoals ras, kleur, geslacht, taal, godsdienst, politieke of andere overtuiging.
+Het weer wordt dit weekend zonnig en de temperaturen stijgen in de middag. Klik hier om meer te lezen over onze nieuwe producten en schrijf je in voor de nieuwsbrief.
+Wij gebruiken cookies om uw ervaring op onze website te verbeteren. Als u vragen heeft, neem dan contact op met ons team, dat elke dag van de week voor u klaarstaat.
+De regering heeft dinsdag aangekondigd dat de nieuwe wet volgend jaar in werking treedt, na maanden van debat tussen de partijen. Wat vindt u ervan?`,
+
+	"pl": `Wszyscy ludzie rodzą się wolni i równi pod względem swej godności i swych praw. Są oni obdarzeni rozumem i sumieniem i powinni postępować wobec innych w duchu braterstwa.
+Każdy człowiek posiada wszystkie prawa i wolności zawarte w niniejszej Deklaracji bez względu na różnice rasy, koloru skóry, płci, języka, wyznania, poglądów politycznych i innych.
+Pogoda w ten weekend będzie słoneczna, a temperatury wzrosną po południu. Kliknij tutaj, aby przeczytać więcej o naszych nowych produktach i zapisz się do newslettera.
+Używamy plików cookie, aby poprawić jakość korzystania z naszej strony. Jeśli masz pytania, skontaktuj się z naszym zespołem, który jest dostępny codziennie przez cały tydzień.
+Rząd ogłosił we wtorek, że nowa ustawa wejdzie w życie w przyszłym roku, po wielu miesiącach debaty między partiami. Co o tym sądzisz?`,
+
+	"tr": `Bütün insanlar hür, haysiyet ve haklar bakımından eşit doğarlar. Akıl ve vicdana sahiptirler ve birbirlerine karşı kardeşlik zihniyeti ile hareket etmelidirler.
+Herkes, ırk, renk, cinsiyet, dil, din, siyasi veya diğer herhangi bir akide, milli veya içtimai menşe, servet, doğuş veya herhangi diğer bir fark gözetilmeksizin bu Beyannamede ilan olunan tekmil haklardan ve hürriyetlerden istifade edebilir.
+Hafta sonu hava güneşli olacak ve sıcaklıklar öğleden sonra yükselecek. Yeni ürünlerimiz hakkında daha fazla bilgi almak için buraya tıklayın ve bültenimize abone olun.
+Web sitemizdeki deneyiminizi iyileştirmek için çerezler kullanıyoruz. Herhangi bir sorunuz varsa, haftanın her günü hizmet veren ekibimizle iletişime geçin.
+Hükümet salı günü yeni yasanın partiler arasındaki aylarca süren tartışmaların ardından gelecek yıl yürürlüğe gireceğini açıkladı. Siz bu konuda ne düşünüyorsunuz?`,
+
+	"sv": `Alla människor är födda fria och lika i värde och rättigheter. De har utrustats med förnuft och samvete och bör handla gentemot varandra i en anda av broderskap.
+Var och en är berättigad till alla de fri- och rättigheter som uttalas i denna förklaring utan åtskillnad av något slag, såsom ras, hudfärg, kön, språk, religion, politisk eller annan uppfattning.
+Vädret blir soligt i helgen och temperaturen stiger under eftermiddagen. Klicka här för att läsa mer om våra nya produkter och prenumerera på vårt nyhetsbrev.
+Vi använder kakor för att förbättra din upplevelse på vår webbplats. Om du har några frågor är du välkommen att kontakta vår kundtjänst, som är öppen alla dagar i veckan.
+Regeringen meddelade på tisdagen att den nya lagen träder i kraft nästa år, efter flera månaders debatt mellan partierna. Vad tycker du om det?`,
+
+	"id": `Semua orang dilahirkan merdeka dan mempunyai martabat dan hak-hak yang sama. Mereka dikaruniai akal dan hati nurani dan hendaknya bergaul satu sama lain dalam semangat persaudaraan.
+Setiap orang berhak atas semua hak dan kebebasan yang tercantum di dalam Pernyataan ini dengan tidak ada pengecualian apapun, seperti ras, warna kulit, jenis kelamin, bahasa, agama, politik atau pendapat yang berlainan.
+Cuaca akan cerah pada akhir pekan ini dan suhu akan naik pada sore hari. Klik di sini untuk membaca lebih lanjut tentang produk baru kami dan berlangganan buletin kami.
+Kami menggunakan cookie untuk meningkatkan pengalaman Anda di situs web kami. Jika Anda memiliki pertanyaan, silakan hubungi tim kami yang tersedia setiap hari dalam seminggu.
+Pemerintah mengumumkan pada hari Selasa bahwa undang-undang yang baru akan mulai berlaku tahun depan, setelah perdebatan berbulan-bulan antara partai. Bagaimana pendapat Anda?`,
+
+	"vi": `Tất cả mọi người sinh ra đều được tự do và bình đẳng về nhân phẩm và quyền. Mọi con người đều được tạo hóa ban cho lý trí và lương tâm và cần phải đối xử với nhau trong tình anh em.
+Mọi người đều được hưởng tất cả những quyền và tự do nêu trong Bản Tuyên ngôn này, không phân biệt đối xử vì bất kỳ lý do nào như chủng tộc, màu da, giới tính, ngôn ngữ, tôn giáo, chính kiến hay quan điểm khác.
+Thời tiết cuối tuần này sẽ có nắng và nhiệt độ tăng vào buổi chiều. Nhấn vào đây để đọc thêm về các sản phẩm mới của chúng tôi và đăng ký nhận bản tin.
+Chúng tôi sử dụng cookie để cải thiện trải nghiệm của bạn trên trang web. Nếu bạn có câu hỏi, vui lòng liên hệ với đội ngũ hỗ trợ của chúng tôi, luôn sẵn sàng mỗi ngày trong tuần.
+Chính phủ đã thông báo vào thứ Ba rằng luật mới sẽ có hiệu lực vào năm tới, sau nhiều tháng tranh luận giữa các đảng. Bạn nghĩ gì về điều này?`,
+
+	"cs": `Všichni lidé rodí se svobodní a sobě rovní co do důstojnosti a práv. Jsou nadáni rozumem a svědomím a mají spolu jednat v duchu bratrství.
+Každý má všechna práva a všechny svobody, stanovené touto deklarací, bez jakéhokoli rozlišování podle rasy, barvy, pohlaví, jazyka, náboženství, politického nebo jiného smýšlení.
+Počasí bude o víkendu slunečné a teploty odpoledne stoupnou. Klikněte sem a přečtěte si více o našich nových výrobcích a přihlaste se k odběru novinek.
+Používáme soubory cookie, abychom zlepšili vaše zkušenosti na našem webu. Máte-li jakékoli dotazy, kontaktujte prosím náš tým, který je k dispozici každý den v týdnu.
+Vláda v úterý oznámila, že nový zákon vstoupí v platnost příští rok, po měsících debat mezi stranami. Co si o tom myslíte?`,
+
+	"ro": `Toate ființele umane se nasc libere și egale în demnitate și în drepturi. Ele sunt înzestrate cu rațiune și conștiință și trebuie să se comporte unele față de altele în spiritul fraternității.
+Fiecare om se poate prevala de toate drepturile și libertățile proclamate în prezenta Declarație fără niciun fel de deosebire ca, de pildă, deosebirea de rasă, culoare, sex, limbă, religie, opinie politică sau orice altă opinie.
+Vremea va fi însorită în acest weekend, iar temperaturile vor crește după-amiază. Faceți clic aici pentru a citi mai multe despre noile noastre produse și abonați-vă la buletinul informativ.
+Folosim module cookie pentru a vă îmbunătăți experiența pe site-ul nostru. Dacă aveți întrebări, vă rugăm să contactați echipa noastră, care este disponibilă în fiecare zi a săptămânii.
+Guvernul a anunțat marți că noua lege va intra în vigoare anul viitor, după luni de dezbateri între partide. Ce părere aveți despre asta?`,
+
+	"hu": `Minden emberi lény szabadnak születik és egyenlő méltósága és joga van. Az emberek, ésszel és lelkiismerettel bírván, egymással szemben testvéri szellemben kell hogy viseltessenek.
+Mindenki, bármely megkülönböztetésre, nevezetesen fajra, színre, nemre, nyelvre, vallásra, politikai vagy bármely más véleményre tekintet nélkül hivatkozhat a jelen Nyilatkozatban kinyilvánított összes jogokra és szabadságokra.
+Az időjárás a hétvégén napos lesz, a hőmérséklet délután emelkedik. Kattintson ide, hogy többet megtudjon új termékeinkről, és iratkozzon fel hírlevelünkre.
+Sütiket használunk, hogy javítsuk az Ön élményét a weboldalunkon. Ha kérdése van, kérjük, forduljon ügyfélszolgálatunkhoz, amely a hét minden napján elérhető.
+A kormány kedden bejelentette, hogy az új törvény jövőre lép hatályba, miután a pártok hónapokig vitatkoztak róla. Ön mit gondol erről?`,
+
+	"ru": `Все люди рождаются свободными и равными в своем достоинстве и правах. Они наделены разумом и совестью и должны поступать в отношении друг друга в духе братства.
+Каждый человек должен обладать всеми правами и всеми свободами, провозглашенными настоящей Декларацией, без какого бы то ни было различия, как-то в отношении расы, цвета кожи, пола, языка, религии, политических или иных убеждений.
+Погода в эти выходные будет солнечной, а температура поднимется во второй половине дня. Нажмите здесь, чтобы узнать больше о наших новых продуктах, и подпишитесь на рассылку.
+Мы используем файлы cookie, чтобы сделать ваше пребывание на нашем сайте удобнее. Если у вас есть вопросы, пожалуйста, свяжитесь с нашей службой поддержки, которая работает каждый день.
+Правительство объявило во вторник, что новый закон вступит в силу в следующем году, после нескольких месяцев споров между партиями. Что вы об этом думаете?`,
+
+	"uk": `Всі люди народжуються вільними і рівними у своїй гідності та правах. Вони наділені розумом і совістю і повинні діяти у відношенні один до одного в дусі братерства.
+Кожна людина повинна мати всі права і всі свободи, проголошені цією Декларацією, незалежно від раси, кольору шкіри, статі, мови, релігії, політичних або інших переконань.
+Погода на цих вихідних буде сонячною, а температура підвищиться в другій половині дня. Натисніть тут, щоб дізнатися більше про наші нові продукти, і підпишіться на розсилку.
+Ми використовуємо файли cookie, щоб покращити ваш досвід на нашому сайті. Якщо у вас є питання, будь ласка, зв'яжіться з нашою службою підтримки, яка працює щодня.
+Уряд оголосив у вівторок, що новий закон набуде чинності наступного року, після кількох місяців суперечок між партіями. Що ви про це думаєте?`,
+
+	"ar": `يولد جميع الناس أحرارًا متساوين في الكرامة والحقوق. وقد وهبوا عقلاً وضميرًا وعليهم أن يعامل بعضهم بعضًا بروح الإخاء.
+لكل إنسان حق التمتع بكافة الحقوق والحريات الواردة في هذا الإعلان، دون أي تمييز، كالتمييز بسبب العنصر أو اللون أو الجنس أو اللغة أو الدين أو الرأي السياسي أو أي رأي آخر.
+سيكون الطقس مشمسًا في عطلة نهاية الأسبوع وسترتفع درجات الحرارة في فترة بعد الظهر. انقر هنا لقراءة المزيد عن منتجاتنا الجديدة واشترك في النشرة الإخبارية.
+نحن نستخدم ملفات تعريف الارتباط لتحسين تجربتك على موقعنا. إذا كانت لديك أي أسئلة، يرجى الاتصال بفريق الدعم لدينا المتاح كل يوم من أيام الأسبوع.
+أعلنت الحكومة يوم الثلاثاء أن القانون الجديد سيدخل حيز التنفيذ العام المقبل، بعد أشهر من النقاش بين الأحزاب. ما رأيك في ذلك؟`,
+
+	"fa": `تمام افراد بشر آزاد به دنیا می‌آیند و از لحاظ حیثیت و حقوق با هم برابرند. همه دارای عقل و وجدان هستند و باید نسبت به یکدیگر با روح برادری رفتار کنند.
+هر کس می‌تواند بدون هیچ گونه تمایز، مخصوصاً از حیث نژاد، رنگ، جنس، زبان، مذهب، عقیده سیاسی یا هر عقیده دیگر، از تمام حقوق و کلیه آزادی‌هایی که در اعلامیه حاضر ذکر شده است، بهره‌مند گردد.
+هوا در این آخر هفته آفتابی خواهد بود و دما در بعد از ظهر افزایش می‌یابد. برای خواندن مطالب بیشتر درباره محصولات جدید ما اینجا کلیک کنید و در خبرنامه عضو شوید.
+ما از کوکی‌ها برای بهبود تجربه شما در وب‌سایت خود استفاده می‌کنیم. اگر سؤالی دارید، لطفاً با تیم پشتیبانی ما که هر روز هفته در دسترس است تماس بگیرید.
+دولت روز سه‌شنبه اعلام کرد که قانون جدید پس از ماه‌ها بحث میان احزاب، از سال آینده اجرا خواهد شد. نظر شما درباره این موضوع چیست؟`,
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"time"
 
@@ -12,6 +13,11 @@ import (
 )
 
 func main() {
+	cfg := config.DefaultConfig()
+	flag.BoolVar(&cfg.Continuous, "continuous", cfg.Continuous, "keep running and revisit pages as they come due")
+	flag.StringVar(&cfg.RecrawlDatabase, "recrawl", cfg.RecrawlDatabase, "AWF `database` to import recrawl validators from")
+	flag.Parse()
+
 	// Read configuration
 	startURLs, err := utils.ReadConfig("config.rcf")
 	if err != nil {
@@ -32,7 +38,7 @@ func main() {
 
 Welcome to Rake, the web crawler! 
 	`)
-	fmt.Printf("Loaded configuration: %d workers, rate limit: %d requests/sec\n", config.DefaultConfig().WorkerCount, config.DefaultConfig().RateLimit)
+	fmt.Printf("Loaded configuration: %d workers, rate limit: %d requests/sec per host\n", cfg.WorkerCount, cfg.RateLimit)
 	fmt.Printf("Starting URLs: %v\n", startURLs)
 
 	// Wait 2 seconds before starting the crawler
@@ -45,11 +51,22 @@ Welcome to Rake, the web crawler!
 	// Initialize storage
 	storage.Init()
 
+	crawler := crawler.NewCrawler(cfg)
+
+	// Continuous mode runs until a signal stops it
+	if cfg.Continuous {
+		crawler.Run(context.Background(), startURLs)
+		storage.Close()
+		return
+	}
+
 	// Create a context with a timeout 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 	defer cancel() // Make sure the context is canceled when the program exits
 
 	// Start the crawler
-	crawler := crawler.NewCrawler(config.DefaultConfig())
 	crawler.Start(ctx, startURLs) // Pass the context and startURLs
+
+	// Flush buffered records; they back the next run's checkpoint and 304s
+	storage.Close()
 }
\ No newline at end of file
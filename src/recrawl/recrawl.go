@@ -0,0 +1,114 @@
+package recrawl
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	"webcrawler/storage"
+	"webcrawler/types"
+)
+
+const storeFile = "validators.db"
+
+// Entry is what we remember about a page between crawls
+type Entry struct {
+	Validators types.Validators `msgpack:"validators"` // Sent back as If-None-Match / If-Modified-Since
+	Links      []string         `msgpack:"links"`      // Outlinks, followed again when the page is unchanged
+	Feed       bool             `msgpack:"feed"`       // An RSS or Atom feed, which is not stored as a page
+}
+
+// Store keeps validators per URL in the state directory so daily recrawls
+// can ask servers for changes only
+type Store struct {
+	mu      sync.Mutex
+	dir     string
+	entries map[string]Entry
+}
+
+func New(dir string) *Store {
+	return &Store{dir: dir, entries: make(map[string]Entry)}
+}
+
+// Load reads the store saved by the last crawl, if there is one
+func (s *Store) Load() error {
+	data, err := os.ReadFile(filepath.Join(s.dir, storeFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return msgpack.Unmarshal(data, &s.entries)
+}
+
+// LoadAWF imports validators from an existing AWF database, such as the
+// one blower writes. Entries already in the store win
+func (s *Store) LoadAWF(filename string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	err := storage.ReadRecords(filename, func(page types.PageData) {
+		if page.Validators == (types.Validators{}) {
+			return
+		}
+		if _, exists := s.entries[page.URL]; exists {
+			return
+		}
+		entry := Entry{Validators: page.Validators}
+		for _, link := range page.Links {
+			entry.Links = append(entry.Links, link.URL)
+		}
+		s.entries[page.URL] = entry
+		count++
+	})
+	return count, err
+}
+
+// Get returns what the last crawl recorded for a URL
+func (s *Store) Get(url string) (Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[url]
+	return entry, ok
+}
+
+// Put records the validators and outlinks of a freshly crawled page
+func (s *Store) Put(url string, entry Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry.Validators == (types.Validators{}) {
+		// Nothing to send next time, so nothing worth remembering
+		delete(s.entries, url)
+		return
+	}
+	s.entries[url] = entry
+}
+
+// Save writes the store to the state directory atomically
+func (s *Store) Save() error {
+	s.mu.Lock()
+	data, err := msgpack.Marshal(s.entries)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	tmp := filepath.Join(s.dir, storeFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(s.dir, storeFile))
+}
@@ -0,0 +1,237 @@
+package revisit
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	"webcrawler/config"
+	"webcrawler/types"
+)
+
+const (
+	scheduleFile  = "revisit.db"
+	historyWindow = 20 // Visits of history the change rate is estimated from
+)
+
+// Upper bounds implied by a sitemap <changefreq>
+var changeFreqs = map[string]time.Duration{
+	"always":  0, // Clamped to RevisitMin
+	"hourly":  time.Hour,
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+	"yearly":  365 * 24 * time.Hour,
+}
+
+// Entry is the visit history and next visit time of one URL
+type Entry struct {
+	URL        string        `msgpack:"url"`
+	Depth      int           `msgpack:"depth"`       // Crawl depth, kept for links found on revisits
+	Hash       uint64        `msgpack:"hash"`        // Content hash at the last visit
+	LastVisit  time.Time     `msgpack:"last_visit"`  // When the page was last fetched
+	NextVisit  time.Time     `msgpack:"next_visit"`  // When the page is due again
+	Interval   time.Duration `msgpack:"interval"`    // Current revisit interval
+	Visits     float64       `msgpack:"visits"`      // Revisits compared against the previous hash
+	Changes    float64       `msgpack:"changes"`     // Revisits that found a different hash
+	Observed   time.Duration `msgpack:"observed"`    // Time covered by those revisits
+	ChangeFreq string        `msgpack:"change_freq"` // Sitemap changefreq, an upper bound on Interval
+	Origin     types.Origin  `msgpack:"origin"`      // Seed and off-site hops, for scoping links found on revisits
+}
+
+// Visit is the outcome of fetching a URL
+type Visit struct {
+	URL         string
+	Depth       int
+	Hash        uint64 // Content hash, ignored when NotModified
+	NotModified bool   // The server answered 304
+	ChangeFreq  string // Sitemap changefreq, "" if none
+	Origin      types.Origin
+}
+
+// Schedule tracks when every crawled URL should be fetched again, adapting
+// each interval to how often the page has been seen to change
+type Schedule struct {
+	config   *config.Config
+	mu       sync.Mutex
+	entries  map[string]*Entry
+	inFlight map[string]bool // Handed out by Due and not yet recorded
+}
+
+func New(cfg *config.Config) *Schedule {
+	return &Schedule{
+		config:   cfg,
+		entries:  make(map[string]*Entry),
+		inFlight: make(map[string]bool),
+	}
+}
+
+// Load reads the schedule saved in the state directory, if there is one
+func (s *Schedule) Load() error {
+	data, err := os.ReadFile(filepath.Join(s.config.StateDir, scheduleFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return msgpack.Unmarshal(data, &s.entries)
+}
+
+// Save writes the schedule to the state directory atomically
+func (s *Schedule) Save() error {
+	s.mu.Lock()
+	data, err := msgpack.Marshal(s.entries)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(s.config.StateDir, 0755); err != nil {
+		return err
+	}
+	tmp := filepath.Join(s.config.StateDir, scheduleFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(s.config.StateDir, scheduleFile))
+}
+
+// Len returns the number of scheduled URLs
+func (s *Schedule) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// Due returns the URLs whose next visit has come, marking them in flight
+// until Record or Release is called for them
+func (s *Schedule) Due(now time.Time) []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []Entry
+	for url, entry := range s.entries {
+		if s.inFlight[url] || entry.NextVisit.After(now) {
+			continue
+		}
+		s.inFlight[url] = true
+		due = append(due, *entry)
+	}
+	return due
+}
+
+// NextDue returns when the first URL not in flight comes due, or false if
+// there is none
+func (s *Schedule) NextDue() (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var next time.Time
+	found := false
+	for url, entry := range s.entries {
+		if s.inFlight[url] || (found && !entry.NextVisit.Before(next)) {
+			continue
+		}
+		next, found = entry.NextVisit, true
+	}
+	return next, found
+}
+
+// Record updates a URL's change history after a visit and schedules the next
+func (s *Schedule) Record(visit Visit, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.inFlight, visit.URL)
+
+	entry, exists := s.entries[visit.URL]
+	if !exists {
+		entry = &Entry{
+			URL:      visit.URL,
+			Depth:    visit.Depth,
+			Hash:     visit.Hash,
+			Interval: s.config.RevisitInitial,
+		}
+		s.entries[visit.URL] = entry
+	} else {
+		changed := !visit.NotModified && visit.Hash != entry.Hash
+		if !visit.NotModified {
+			entry.Hash = visit.Hash
+		}
+
+		entry.Visits++
+		entry.Observed += now.Sub(entry.LastVisit)
+		if changed {
+			entry.Changes++
+		}
+
+		// Old history fades so the estimate follows pages whose habits change
+		if entry.Visits > historyWindow {
+			scale := historyWindow / entry.Visits
+			entry.Visits *= scale
+			entry.Changes *= scale
+			entry.Observed = time.Duration(float64(entry.Observed) * scale)
+		}
+		entry.Interval = s.estimate(entry)
+	}
+
+	if visit.ChangeFreq != "" {
+		entry.ChangeFreq = visit.ChangeFreq
+	}
+	if visit.Origin.Seed != "" {
+		entry.Origin = visit.Origin
+	}
+	entry.Interval = s.clamp(entry.Interval, entry.ChangeFreq)
+	entry.LastVisit = now
+	entry.NextVisit = now.Add(entry.Interval)
+}
+
+// Release returns a URL handed out by Due that could not be visited, trying
+// it again after its current interval
+func (s *Schedule) Release(url string, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.inFlight[url] {
+		return
+	}
+	delete(s.inFlight, url)
+	if entry, ok := s.entries[url]; ok {
+		entry.NextVisit = now.Add(entry.Interval)
+	}
+}
+
+// estimate derives a revisit interval from the change history using the
+// Cho & Garcia-Molina estimator for a Poisson process: with n revisits at
+// average interval I that found X changes, the change rate is
+// -ln((n - X + 0.5) / (n + 0.5)) / I. We revisit once per expected change
+func (s *Schedule) estimate(entry *Entry) time.Duration {
+	if entry.Visits < 1 || entry.Observed <= 0 {
+		return s.config.RevisitInitial
+	}
+
+	average := float64(entry.Observed) / entry.Visits
+	changeRate := -math.Log((entry.Visits-entry.Changes+0.5)/(entry.Visits+0.5)) / average
+	if changeRate <= 0 {
+		return s.config.RevisitMax
+	}
+	return time.Duration(math.Min(1/changeRate, float64(s.config.RevisitMax)))
+}
+
+// clamp keeps an interval within the configured range and under the
+// sitemap changefreq, if any
+func (s *Schedule) clamp(interval time.Duration, changeFreq string) time.Duration {
+	if bound, ok := changeFreqs[changeFreq]; ok && interval > bound {
+		interval = bound
+	}
+	return min(max(interval, s.config.RevisitMin), s.config.RevisitMax)
+}
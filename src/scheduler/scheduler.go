@@ -0,0 +1,481 @@
+package scheduler
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"webcrawler/config"
+	"webcrawler/frontier"
+	"webcrawler/utils"
+)
+
+// Scheduler hands out URLs so that no host is hit faster than its rate limit
+// or with more requests in flight than it allows
+type Scheduler struct {
+	config  *config.Config
+	mu      sync.Mutex
+	hosts   map[string]*host
+	delays  map[string]time.Duration // robots.txt Crawl-delay per host
+	slowed  map[string]int           // Times each host has asked us to back off
+	later   delayedQueue             // URLs waiting for a retry time
+	size    int                      // URLs waiting in memory across all hosts
+	active  map[string]frontier.Item // Handed out by Next and not Done yet, for checkpoints
+	spill   *frontier.Frontier       // URLs beyond QueueSize, on disk; nil until needed
+	changed chan struct{}            // Closed and replaced whenever the state changes
+	closed  bool
+}
+
+type host struct {
+	queue       []frontier.Item
+	limiter     *rate.Limiter
+	inFlight    int
+	maxInFlight int
+}
+
+func New(cfg *config.Config) *Scheduler {
+	return &Scheduler{
+		config:  cfg,
+		hosts:   make(map[string]*host),
+		delays:  make(map[string]time.Duration),
+		slowed:  make(map[string]int),
+		active:  make(map[string]frontier.Item),
+		changed: make(chan struct{}),
+	}
+}
+
+// HostKey returns the key URLs are grouped by, the lowercased host
+func HostKey(targetURL string) string {
+	parsedURL, err := url.Parse(targetURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsedURL.Host)
+}
+
+// Push adds a URL to its host's queue. Once QueueSize URLs are in memory,
+// later ones go to the disk frontier until there is room again. It never
+// blocks, so it may be called while holding other locks
+func (s *Scheduler) Push(item frontier.Item) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	// Once URLs have spilled, newer ones queue up behind them
+	if s.size >= s.config.QueueSize || s.spill != nil && s.spill.Len() > 0 {
+		if s.spillURL(item) {
+			return
+		}
+	}
+	h := s.hostFor(HostKey(item.URL))
+	h.queue = append(h.queue, item)
+	s.size++
+	s.broadcast()
+}
+
+// spillURL writes a URL to the disk frontier, opening it in the state
+// directory on first use. On failure the URL stays in memory instead.
+// Must hold s.mu
+func (s *Scheduler) spillURL(item frontier.Item) bool {
+	if s.spill == nil {
+		spill, err := frontier.New(frontier.Dir(s.config.StateDir), s.config.SegmentSize)
+		if err != nil {
+			fmt.Println("\r[Frontier Error]", err)
+			return false
+		}
+		s.spill = spill
+	}
+	if err := s.spill.Push(item); err != nil {
+		fmt.Println("\r[Frontier Error]", err)
+		return false
+	}
+	return true
+}
+
+// refill moves spilled URLs back onto their hosts' queues while there is
+// room in memory. Must hold s.mu
+func (s *Scheduler) refill() {
+	for s.spill != nil && s.spill.Len() > 0 && s.size < s.config.QueueSize {
+		item, ok, err := s.spill.Pop()
+		if err != nil {
+			fmt.Println("\r[Frontier Error]", err)
+			return
+		}
+		if !ok {
+			return
+		}
+		h := s.hostFor(HostKey(item.URL))
+		h.queue = append(h.queue, item)
+		s.size++
+	}
+}
+
+// PushAfter schedules a URL to be handed out again once delay has passed.
+// Used for retries, so it never blocks on a full scheduler
+func (s *Scheduler) PushAfter(item frontier.Item, delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	heap.Push(&s.later, delayed{item: item, readyAt: time.Now().Add(delay)})
+	s.size++
+	s.broadcast()
+}
+
+// Next blocks until some host is allowed another request and returns its
+// next URL. It returns false once the context is done or the scheduler closed
+func (s *Scheduler) Next(ctx context.Context) (frontier.Item, bool) {
+	s.mu.Lock()
+	for {
+		if s.closed {
+			s.mu.Unlock()
+			return frontier.Item{}, false
+		}
+
+		item, ok, wait := s.pick()
+		if ok {
+			s.active[item.URL] = item
+			s.broadcast()
+			s.mu.Unlock()
+			return item, true
+		}
+
+		changed := s.changed
+		s.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return frontier.Item{}, false
+		case <-changed:
+		case <-timer.C:
+		}
+		timer.Stop()
+		s.mu.Lock()
+	}
+}
+
+// pick takes a URL from a ready host. If none is ready it returns how long
+// until one might be. Must hold s.mu
+func (s *Scheduler) pick() (frontier.Item, bool, time.Duration) {
+	wait := time.Second
+
+	// Move retries whose time has come onto their host's queue
+	now := time.Now()
+	for s.later.Len() > 0 && !s.later[0].readyAt.After(now) {
+		retry := heap.Pop(&s.later).(delayed)
+		h := s.hostFor(HostKey(retry.item.URL))
+		h.queue = append(h.queue, retry.item)
+	}
+	if s.later.Len() > 0 {
+		wait = min(wait, s.later[0].readyAt.Sub(now))
+	}
+	s.refill()
+
+	for key, h := range s.hosts {
+		if len(h.queue) == 0 {
+			// Forget idle hosts once their limiter has fully recovered
+			if h.inFlight == 0 && h.limiter.Tokens() >= float64(h.limiter.Burst()) {
+				delete(s.hosts, key)
+			}
+			continue
+		}
+		if h.inFlight >= h.maxInFlight {
+			continue
+		}
+		if !h.limiter.Allow() {
+			if d := untilToken(h.limiter); d < wait {
+				wait = d
+			}
+			continue
+		}
+
+		item := h.queue[0]
+		h.queue[0] = frontier.Item{}
+		h.queue = h.queue[1:]
+		h.inFlight++
+		s.size--
+		return item, true, 0
+	}
+	return frontier.Item{}, false, wait
+}
+
+// Acquire waits until the host of targetURL allows another request and
+// takes an in-flight slot for it, for requests made outside Next such as
+// image lookups. Returns false if ctx ends first. Release it with Done
+func (s *Scheduler) Acquire(ctx context.Context, targetURL string) bool {
+	return s.wait(ctx, targetURL, true)
+}
+
+// Throttle waits until the host of targetURL allows another request, for
+// requests made under an in-flight slot already held, such as image lookups
+// on the host of the page being crawled. Returns false if ctx ends first
+func (s *Scheduler) Throttle(ctx context.Context, targetURL string) bool {
+	return s.wait(ctx, targetURL, false)
+}
+
+// wait blocks until the host's limiter has a token and, if slot is set,
+// an in-flight slot is free, and takes them
+func (s *Scheduler) wait(ctx context.Context, targetURL string, slot bool) bool {
+	key := HostKey(targetURL)
+
+	s.mu.Lock()
+	for {
+		if s.closed {
+			s.mu.Unlock()
+			return false
+		}
+
+		h := s.hostFor(key)
+		wait := time.Second
+		if !slot || h.inFlight < h.maxInFlight {
+			if h.limiter.Allow() {
+				if slot {
+					h.inFlight++
+				}
+				s.mu.Unlock()
+				return true
+			}
+			wait = untilToken(h.limiter)
+		}
+
+		changed := s.changed
+		s.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-changed:
+		case <-timer.C:
+		}
+		timer.Stop()
+		s.mu.Lock()
+	}
+}
+
+// Done releases the in-flight slot taken by a URL returned from Next or
+// Acquire
+func (s *Scheduler) Done(targetURL string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.active, targetURL)
+	if h, ok := s.hosts[HostKey(targetURL)]; ok && h.inFlight > 0 {
+		h.inFlight--
+		s.broadcast()
+	}
+}
+
+// SetCrawlDelay slows a host down to at most one request per delay, as
+// asked by its robots.txt. Delays above MaxCrawlDelay are capped
+func (s *Scheduler) SetCrawlDelay(key string, delay time.Duration) {
+	if delay > s.config.MaxCrawlDelay {
+		delay = s.config.MaxCrawlDelay
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.delays[key] == delay {
+		return
+	}
+	s.delays[key] = delay
+	if h, ok := s.hosts[key]; ok {
+		h.limiter.SetLimit(s.hostRate(key))
+	}
+}
+
+// SlowDown halves the request rate of a host that told us it is overloaded,
+// down to one request per MaxCrawlDelay
+func (s *Scheduler) SlowDown(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rate.Every(s.config.MaxCrawlDelay) >= s.hostRate(key) {
+		return
+	}
+	s.slowed[key]++
+	if h, ok := s.hosts[key]; ok {
+		h.limiter.SetLimit(s.hostRate(key))
+	}
+}
+
+// Len returns the number of URLs waiting to be crawled, on disk included
+func (s *Scheduler) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.spill != nil {
+		return s.size + s.spill.Len()
+	}
+	return s.size
+}
+
+// Checkpoint returns the URLs waiting in memory or in flight, and the state
+// of the disk frontier holding the rest. A URL being retried is both in
+// flight and waiting, and is returned once. Call Commit once the checkpoint
+// is saved
+func (s *Scheduler) Checkpoint() ([]frontier.Item, frontier.State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := make(map[string]frontier.Item, s.size+len(s.active))
+	for _, item := range s.active {
+		items[item.URL] = item
+	}
+	for _, h := range s.hosts {
+		for _, item := range h.queue {
+			items[item.URL] = item
+		}
+	}
+	for _, retry := range s.later {
+		items[retry.item.URL] = retry.item
+	}
+
+	pending := make([]frontier.Item, 0, len(items))
+	for _, item := range items {
+		pending = append(pending, item)
+	}
+	if s.spill == nil {
+		return pending, frontier.State{}, nil
+	}
+	spilled, err := s.spill.Checkpoint()
+	return pending, spilled, err
+}
+
+// Commit drops the disk frontier segments that were read before the last
+// checkpoint, now that it is saved
+func (s *Scheduler) Commit() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.spill != nil {
+		s.spill.Commit()
+	}
+}
+
+// Restore queues the URLs of a checkpoint and reopens its disk frontier,
+// leaving out URLs drop rejects. Returns the number of URLs queued
+func (s *Scheduler) Restore(pending []frontier.Item, spilled frontier.State, drop func(frontier.Item) bool) (int, error) {
+	count := 0
+	if len(spilled.Segments) > 0 {
+		spill, err := frontier.Resume(frontier.Dir(s.config.StateDir), s.config.SegmentSize, spilled, drop)
+		if err != nil {
+			return 0, err
+		}
+		s.mu.Lock()
+		s.spill = spill
+		count = spill.Len()
+		s.mu.Unlock()
+	}
+
+	for _, item := range pending {
+		if !drop(item) {
+			s.Push(item)
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Close wakes every waiting worker and makes Next return false
+func (s *Scheduler) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	if s.spill != nil {
+		s.spill.Close()
+	}
+	s.broadcast()
+}
+
+// hostFor returns the state for a host, creating it with the configured
+// limits. Must hold s.mu
+func (s *Scheduler) hostFor(key string) *host {
+	if h, ok := s.hosts[key]; ok {
+		return h
+	}
+
+	maxInFlight := s.config.HostMaxInFlight
+	if limit, ok := utils.HostLimitFor(key); ok {
+		maxInFlight = limit.MaxInFlight
+	}
+	if maxInFlight < 1 {
+		maxInFlight = 1
+	}
+
+	h := &host{
+		limiter:     rate.NewLimiter(s.hostRate(key), 1),
+		maxInFlight: maxInFlight,
+	}
+	s.hosts[key] = h
+	return h
+}
+
+// hostRate is the configured rate for a host, lowered to honor its
+// Crawl-delay. Must hold s.mu
+func (s *Scheduler) hostRate(key string) rate.Limit {
+	requestsPerSec := float64(s.config.RateLimit)
+	if limit, ok := utils.HostLimitFor(key); ok {
+		requestsPerSec = limit.RateLimit
+	}
+
+	limit := rate.Limit(requestsPerSec)
+	if delay := s.delays[key]; delay > 0 && rate.Every(delay) < limit {
+		limit = rate.Every(delay)
+	}
+	if slowed := s.slowed[key]; slowed > 0 {
+		floor := min(limit, rate.Every(s.config.MaxCrawlDelay))
+		limit = max(limit/rate.Limit(int(1)<<slowed), floor)
+	}
+	return limit
+}
+
+// broadcast wakes everything waiting on a state change. Must hold s.mu
+func (s *Scheduler) broadcast() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// untilToken estimates how long until the limiter has a token again
+func untilToken(limiter *rate.Limiter) time.Duration {
+	if limiter.Limit() <= 0 {
+		return time.Second
+	}
+	missing := 1 - limiter.Tokens()
+	return time.Duration(missing / float64(limiter.Limit()) * float64(time.Second))
+}
+
+// delayed is a URL that may not be handed out before readyAt
+type delayed struct {
+	item    frontier.Item
+	readyAt time.Time
+}
+
+// delayedQueue is a min-heap of delayed URLs ordered by readyAt
+type delayedQueue []delayed
+
+func (q delayedQueue) Len() int           { return len(q) }
+func (q delayedQueue) Less(i, j int) bool { return q[i].readyAt.Before(q[j].readyAt) }
+func (q delayedQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *delayedQueue) Push(x any)        { *q = append(*q, x.(delayed)) }
+
+func (q *delayedQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
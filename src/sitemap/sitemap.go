@@ -0,0 +1,196 @@
+package sitemap
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"webcrawler/types"
+	"webcrawler/utils"
+)
+
+const (
+	maxBytes   = 50 * 1024 * 1024 // Protocol limit for an uncompressed sitemap
+	maxNesting = 2                // Indexes may not nest, but some sites do anyway
+)
+
+// W3C datetime layouts allowed for <lastmod>
+var lastModLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// Getter fetches a sitemap and returns its body, which the caller closes.
+// The crawler's getter goes through the scheduler, so sitemaps get the same
+// politeness as pages
+type Getter func(ctx context.Context, sitemapURL string) (io.ReadCloser, error)
+
+// errLimit stops the walk through a sitemap index once enough entries
+// have been seen
+var errLimit = errors.New("sitemap entry limit reached")
+
+type urlEntry struct {
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod"`
+	ChangeFreq string `xml:"changefreq"`
+	Priority   string `xml:"priority"`
+}
+
+type sitemapEntry struct {
+	Loc string `xml:"loc"`
+}
+
+// Discover returns the sitemaps for the site of siteURL: the Sitemap: lines
+// of its robots.txt, or /sitemap.xml when there are none
+func Discover(siteURL, userAgent string) []string {
+	if sitemaps := utils.Sitemaps(siteURL, userAgent); len(sitemaps) > 0 {
+		return sitemaps
+	}
+
+	fallback := utils.ResolveURL(siteURL, "/sitemap.xml")
+	if fallback == "" || !utils.CanCrawl(fallback, userAgent) {
+		return nil
+	}
+	return []string{fallback}
+}
+
+// Fetch reads a sitemap or sitemap index with get, following indexes, and
+// calls fn for every page it lists until limit entries have been seen.
+// Pages and child sitemaps on another host than the sitemap listing them
+// are skipped, as the protocol requires. Returns the number of entries
+// passed to fn
+func Fetch(ctx context.Context, get Getter, sitemapURL string, limit int, fn func(types.SitemapEntry)) (int, error) {
+	if limit <= 0 {
+		return 0, nil
+	}
+	count := 0
+	err := fetch(ctx, get, sitemapURL, 0, func(entry types.SitemapEntry) bool {
+		fn(entry)
+		count++
+		return count < limit
+	})
+	if errors.Is(err, errLimit) {
+		err = nil
+	}
+	return count, err
+}
+
+// fetch walks one sitemap or index. It returns errLimit once fn asks to
+// stop, so the indexes above it stop too
+func fetch(ctx context.Context, get Getter, sitemapURL string, nesting int, fn func(types.SitemapEntry) bool) error {
+	host := hostOf(sitemapURL)
+	raw, err := get(ctx, sitemapURL)
+	if err != nil {
+		return err
+	}
+	defer raw.Close()
+
+	body, err := decompress(raw)
+	if err != nil {
+		return err
+	}
+
+	decoder := xml.NewDecoder(io.LimitReader(body, maxBytes))
+	decoder.Strict = false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "url":
+			var entry urlEntry
+			if err := decoder.DecodeElement(&entry, &start); err != nil {
+				return err
+			}
+			loc := strings.TrimSpace(entry.Loc)
+			if loc == "" || hostOf(loc) != host {
+				continue
+			}
+			if !fn(parseEntry(loc, entry)) {
+				return errLimit
+			}
+		case "sitemap":
+			var entry sitemapEntry
+			if err := decoder.DecodeElement(&entry, &start); err != nil {
+				return err
+			}
+			loc := strings.TrimSpace(entry.Loc)
+			if loc == "" || nesting >= maxNesting || hostOf(loc) != host {
+				continue
+			}
+			err := fetch(ctx, get, loc, nesting+1, fn)
+			if errors.Is(err, errLimit) {
+				return err
+			}
+			if err != nil {
+				// One broken child sitemap should not stop the rest
+				fmt.Println("\r[Sitemap Failed]", loc, err)
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// hostOf returns a URL's lowercased host and port, "" if it has none
+func hostOf(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Host)
+}
+
+// decompress unwraps gzip-compressed sitemaps, detected by their magic bytes
+// so it works whatever the URL or Content-Type says
+func decompress(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	magic, err := buffered.Peek(2)
+	if err != nil || magic[0] != 0x1f || magic[1] != 0x8b {
+		return buffered, nil
+	}
+	return gzip.NewReader(buffered)
+}
+
+func parseEntry(loc string, entry urlEntry) types.SitemapEntry {
+	result := types.SitemapEntry{
+		URL:        loc,
+		ChangeFreq: strings.ToLower(strings.TrimSpace(entry.ChangeFreq)),
+		Priority:   0.5, // Protocol default
+	}
+
+	lastMod := strings.TrimSpace(entry.LastMod)
+	for _, layout := range lastModLayouts {
+		if t, err := time.Parse(layout, lastMod); err == nil {
+			result.LastMod = t
+			break
+		}
+	}
+
+	if priority, err := strconv.ParseFloat(strings.TrimSpace(entry.Priority), 64); err == nil && priority >= 0 && priority <= 1 {
+		result.Priority = priority
+	}
+	return result
+}
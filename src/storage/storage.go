@@ -3,11 +3,14 @@ package storage
 import (
 	"bufio"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 
 	"webcrawler/types"
 
@@ -19,8 +22,11 @@ var (
 	fileHandle  *os.File
 	writer      *bufio.Writer
 	storageFile = "crawl_data.awf" // Default filename
+	offset      int64              // Length of the storage file once the buffer is flushed
 	shutdown    = make(chan struct{})
 	sigChan     = make(chan os.Signal, 1)
+	hooksMu     sync.Mutex
+	hooks       []func()
 )
 
 // Initialize storage (thread-safe)
@@ -30,11 +36,28 @@ func Init() {
 	go func() {
 		<-sigChan
 		fmt.Println("\n[Signal Received] Flushing data before exit...")
+		runShutdownHooks()
 		Close()
 		os.Exit(0)
 	}()
 }
 
+// OnShutdown registers a function to run when a signal stops the crawler,
+// before the storage file is closed
+func OnShutdown(fn func()) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	hooks = append(hooks, fn)
+}
+
+func runShutdownHooks() {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	for _, fn := range hooks {
+		fn()
+	}
+}
+
 // SetStorageFile configures output filename (thread-safe)
 func SetStorageFile(filename string) {
 	fileMutex.Lock()
@@ -58,8 +81,14 @@ func initStorage() error {
 		if err != nil {
 			return err
 		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return err
+		}
 		fileHandle = f
 		writer = bufio.NewWriter(f)
+		offset = info.Size()
 		go func() {
 			<-shutdown
 			Close()
@@ -77,6 +106,11 @@ func SaveData(data types.PageData) {
 		}
 	}
 
+	// Blower keeps the newest record of a page, so every record is dated
+	if data.Crawled.IsZero() {
+		data.Crawled = time.Now()
+	}
+
 	// MessagePack serialization
 	binData, err := msgpack.Marshal(data)
 	if err != nil {
@@ -108,6 +142,7 @@ func writeRecord(data []byte) error {
 	if _, err := writer.Write(data); err != nil {
 		return err
 	}
+	offset += int64(8 + len(data))
 
 	// Flush every 100 writes
 	if writer.Buffered() > 100*1024 { // 100KB buffer
@@ -116,6 +151,94 @@ func writeRecord(data []byte) error {
 	return nil
 }
 
+// Flush writes buffered records to disk (thread-safe)
+func Flush() error {
+	fileMutex.Lock()
+	defer fileMutex.Unlock()
+
+	if writer == nil {
+		return nil
+	}
+	return writer.Flush()
+}
+
+// Offset returns the length the storage file has once buffered records are
+// written, so a checkpoint can tell which records were saved after it
+func Offset() (int64, error) {
+	fileMutex.Lock()
+	defer fileMutex.Unlock()
+
+	if fileHandle != nil {
+		return offset, nil
+	}
+	info, err := os.Stat(storageFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// ReadSaved calls fn for every record in the storage file from offset on
+func ReadSaved(from int64, fn func(types.PageData)) error {
+	fileMutex.Lock()
+	filename := storageFile
+	fileMutex.Unlock()
+
+	return readRecords(filename, from, fn)
+}
+
+// ReadRecords calls fn for every record in an AWF file. A missing file has
+// no records, and a torn tail left by a crash ends the file
+func ReadRecords(filename string, fn func(types.PageData)) error {
+	return readRecords(filename, 0, fn)
+}
+
+func readRecords(filename string, from int64, fn func(types.PageData)) error {
+	file, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(from, io.SeekStart); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(file)
+	for {
+		var length uint64
+		if err := binary.Read(reader, binary.LittleEndian, &length); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil
+			}
+			return err
+		}
+
+		// Same sanity limit blower uses
+		const maxLength = 10 * 1024 * 1024
+		if length > maxLength {
+			return nil
+		}
+
+		data := make([]byte, length)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil
+		}
+
+		var page types.PageData
+		if err := msgpack.Unmarshal(data, &page); err != nil {
+			continue
+		}
+		fn(page)
+	}
+}
+
 // Make sure all buffered data is written to the file
 func Close() {
 	fileMutex.Lock()
@@ -129,4 +252,3 @@ func Close() {
 	}
 	fmt.Println("[Storage Closed]")
 }
-
@@ -0,0 +1,291 @@
+package structured
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"webcrawler/types"
+	"webcrawler/utils"
+)
+
+// Layouts seen in datePublished and article:published_time
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// JSON-LD types that describe the site or a part of the page rather than
+// what the page is about
+var supportingTypes = map[string]bool{
+	"breadcrumblist": true, "website": true, "organization": true, "person": true,
+	"imageobject": true, "searchaction": true, "listitem": true, "sitenavigationelement": true,
+	"wpheader": true, "wpfooter": true, "wpsidebar": true, "readaction": true,
+}
+
+// Extract reads JSON-LD, OpenGraph and Twitter Card markup, in that order
+// of preference, into one summary of what the page is. URLs are resolved
+// against pageURL
+func Extract(pageURL string, doc *goquery.Document) types.StructuredData {
+	var data types.StructuredData
+	fromJSONLD(&data, pageURL, doc)
+
+	meta := make(map[string]string)
+	doc.Find("meta[content]").Each(func(i int, s *goquery.Selection) {
+		key := s.AttrOr("property", s.AttrOr("name", ""))
+		key = strings.ToLower(strings.TrimSpace(key))
+		if _, seen := meta[key]; key != "" && !seen {
+			meta[key] = strings.TrimSpace(s.AttrOr("content", ""))
+		}
+	})
+
+	if data.Type == "" {
+		data.Type = meta["og:type"]
+	}
+	if data.Author == "" {
+		data.Author = first(meta["article:author"], meta["twitter:creator"], meta["author"])
+	}
+	if data.Published.IsZero() {
+		data.Published = parseDate(first(meta["article:published_time"], meta["og:published_time"]))
+	}
+	if data.Image == "" {
+		if image := first(meta["og:image"], meta["og:image:url"], meta["og:image:secure_url"], meta["twitter:image"], meta["twitter:image:src"]); image != "" {
+			data.Image = utils.ResolveURL(pageURL, image)
+		}
+	}
+	return data
+}
+
+// fromJSONLD fills data from the page's JSON-LD blocks. The main entity is
+// the first node that is not about the site itself, and a WebPage only if
+// there is nothing more specific
+func fromJSONLD(data *types.StructuredData, pageURL string, doc *goquery.Document) {
+	var nodes []map[string]any
+	doc.Find(`script[type="application/ld+json"]`).Each(func(i int, s *goquery.Selection) {
+		var value any
+		if err := json.Unmarshal([]byte(s.Text()), &value); err != nil {
+			return
+		}
+		nodes = appendNodes(nodes, value)
+	})
+	if len(nodes) == 0 {
+		return
+	}
+
+	// Nodes in a @graph point at each other by @id
+	byID := make(map[string]map[string]any)
+	for _, node := range nodes {
+		if id, ok := node["@id"].(string); ok {
+			byID[id] = node
+		}
+	}
+
+	var main map[string]any
+	for _, node := range nodes {
+		names := nodeTypes(node)
+		if len(names) == 0 {
+			continue
+		}
+		if names[0] == "breadcrumblist" && data.Breadcrumbs == nil {
+			data.Breadcrumbs = breadcrumbs(node, pageURL, byID)
+		}
+		if breadcrumb, ok := resolve(node["breadcrumb"], byID).(map[string]any); ok && data.Breadcrumbs == nil {
+			data.Breadcrumbs = breadcrumbs(breadcrumb, pageURL, byID)
+		}
+
+		if supportingTypes[names[0]] {
+			continue
+		}
+		if main == nil || (isWebPage(main) && !isWebPage(node)) {
+			main = node
+		}
+	}
+	if main == nil {
+		return
+	}
+
+	if name, ok := main["@type"].(string); ok {
+		data.Type = name
+	} else if names, ok := main["@type"].([]any); ok && len(names) > 0 {
+		data.Type, _ = names[0].(string)
+	}
+	data.Author = author(main["author"], byID)
+	data.Published = parseDate(text(main["datePublished"]))
+	if image := imageURL(main["image"], byID); image != "" {
+		data.Image = utils.ResolveURL(pageURL, image)
+	}
+}
+
+// appendNodes flattens arrays and @graph containers into a list of nodes
+func appendNodes(nodes []map[string]any, value any) []map[string]any {
+	switch v := value.(type) {
+	case []any:
+		for _, item := range v {
+			nodes = appendNodes(nodes, item)
+		}
+	case map[string]any:
+		if graph, ok := v["@graph"]; ok {
+			return appendNodes(nodes, graph)
+		}
+		nodes = append(nodes, v)
+	}
+	return nodes
+}
+
+// nodeTypes returns a node's @type values, lowercased
+func nodeTypes(node map[string]any) []string {
+	var names []string
+	switch v := node["@type"].(type) {
+	case string:
+		names = append(names, strings.ToLower(v))
+	case []any:
+		for _, item := range v {
+			if name, ok := item.(string); ok {
+				names = append(names, strings.ToLower(name))
+			}
+		}
+	}
+	return names
+}
+
+func isWebPage(node map[string]any) bool {
+	for _, name := range nodeTypes(node) {
+		if strings.HasSuffix(name, "webpage") {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve follows a {"@id": ...} reference to the node it names
+func resolve(value any, byID map[string]map[string]any) any {
+	if ref, ok := value.(map[string]any); ok && len(ref) == 1 {
+		if id, ok := ref["@id"].(string); ok {
+			if node, ok := byID[id]; ok {
+				return node
+			}
+		}
+	}
+	return value
+}
+
+// author names the first author, who may be a string, a Person or a list
+func author(value any, byID map[string]map[string]any) string {
+	switch v := resolve(value, byID).(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case []any:
+		for _, item := range v {
+			if name := author(item, byID); name != "" {
+				return name
+			}
+		}
+	case map[string]any:
+		return text(v["name"])
+	}
+	return ""
+}
+
+// imageURL takes the first image, which may be a URL, an ImageObject or a
+// list of either
+func imageURL(value any, byID map[string]map[string]any) string {
+	switch v := resolve(value, byID).(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case []any:
+		for _, item := range v {
+			if url := imageURL(item, byID); url != "" {
+				return url
+			}
+		}
+	case map[string]any:
+		return first(text(v["url"]), text(v["contentUrl"]))
+	}
+	return ""
+}
+
+// breadcrumbs lists a BreadcrumbList's items in position order
+func breadcrumbs(list map[string]any, pageURL string, byID map[string]map[string]any) []types.Breadcrumb {
+	items, _ := list["itemListElement"].([]any)
+
+	type positioned struct {
+		position float64
+		crumb    types.Breadcrumb
+	}
+	var crumbs []positioned
+	for i, value := range items {
+		item, ok := resolve(value, byID).(map[string]any)
+		if !ok {
+			continue
+		}
+
+		crumb := types.Breadcrumb{Name: text(item["name"])}
+		switch target := resolve(item["item"], byID).(type) {
+		case string:
+			crumb.URL = target
+		case map[string]any:
+			crumb.URL = first(text(target["@id"]), text(target["url"]))
+			if crumb.Name == "" {
+				crumb.Name = text(target["name"])
+			}
+		}
+		if crumb.URL != "" {
+			crumb.URL = utils.ResolveURL(pageURL, crumb.URL)
+		}
+		if crumb.Name == "" && crumb.URL == "" {
+			continue
+		}
+
+		position := float64(i + 1)
+		switch p := item["position"].(type) {
+		case float64:
+			position = p
+		case string:
+			if parsed, err := strconv.ParseFloat(p, 64); err == nil {
+				position = parsed
+			}
+		}
+		crumbs = append(crumbs, positioned{position, crumb})
+	}
+
+	sort.SliceStable(crumbs, func(i, j int) bool { return crumbs[i].position < crumbs[j].position })
+	result := make([]types.Breadcrumb, 0, len(crumbs))
+	for _, c := range crumbs {
+		result = append(result, c.crumb)
+	}
+	return result
+}
+
+// text returns a JSON-LD value as a string, if it is one
+func text(value any) string {
+	s, _ := value.(string)
+	return strings.TrimSpace(s)
+}
+
+func first(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// parseDate tries each known layout, returning the zero time if none fit
+func parseDate(value string) time.Time {
+	for _, layout := range dateLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed
+		}
+	}
+	return time.Time{}
+}
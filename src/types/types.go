@@ -3,14 +3,76 @@ package types
 import "time"
 
 type PageData struct {
-	URL       	string    `json:"url"`            // Page URL
-	Title     	string    `json:"title"`          // Page title
-	Description string    `json:"description"`    // Page description
-	Meta      	[]Meta    `json:"meta"`           // Page metadata
-	LastModified time.Time `json:"last_modified"` // Page last modified time
-	// Links     	[]string  `json:"links"`          // Page links
-	Language  	string    `json:"language"`       // Page language
-	Favicon   	string    `json:"favicon"`        // Page favicon
+	URL          string           `json:"url"`           // Page URL, where any redirects ended
+	Redirects    []Redirect       `json:"redirects"`     // Redirect hops that led to URL, starting at the requested URL
+	Encoding     string           `json:"encoding"`      // Character encoding the page was sent in, such as shift_jis; stored as UTF-8
+	Truncated    bool             `json:"truncated"`     // The page was longer than MaxBodySize and was cut off
+	Title        string           `json:"title"`         // Page title
+	Description  string           `json:"description"`   // Page description
+	Meta         []Meta           `json:"meta"`          // Page metadata
+	LastModified time.Time        `json:"last_modified"` // Page last modified time
+	Links        []Link           `json:"links"`         // Page links
+	Images       []Image          `json:"images"`        // Page images, for image search
+	Canonical    string           `json:"canonical"`     // URL the page declares canonical, "" if none
+	Alternates   []Alternate      `json:"alternates"`    // hreflang translations of the page
+	Language     string           `json:"language"`      // Language declared by <html lang>, "" if none
+	Detected     string           `json:"detected"`      // Language detected from the text, "" if too little text
+	Confidence   float64          `json:"confidence"`    // Confidence in Detected, from 0 to 1
+	Favicon      string           `json:"favicon"`       // Page favicon
+	Content      string           `json:"content"`       // Main text with boilerplate removed, one block per line
+	WordCount    int              `json:"word_count"`    // Words in Content
+	TextRatio    float64          `json:"text_ratio"`    // Length of Content over the length of the HTML
+	ContentHash  string           `json:"content_hash"`  // SHA-256 of the normalized Content
+	SimHash      uint64           `json:"simhash"`       // 64-bit SimHash of Content for near-duplicate detection
+	Structured   StructuredData   `json:"structured"`    // What the page says it is in JSON-LD, OpenGraph and Twitter Card markup
+	Robots       RobotsDirectives `json:"robots"`        // Effective meta robots and X-Robots-Tag directives
+	Validators   Validators       `json:"validators"`    // Cache validators for conditional recrawls
+	Unchanged    bool             `json:"unchanged"`     // 304 Not Modified: keep the previously stored content
+	Crawled      time.Time        `json:"crawled"`       // When the record was written, zero in older crawls
+	Requested    string           `json:"requested"`     // URL the crawl asked for, before any redirects
+	Queued       []string         `json:"queued"`        // Links the page added to the crawl, queued again when resuming
+}
+
+// Redirect is one hop of a redirect chain
+type Redirect struct {
+	URL    string `json:"url"`    // URL that answered with a redirect
+	Status int    `json:"status"` // Its status code: 301, 302, 303, 307 or 308
+}
+
+// Alternate is a translation of a page declared with hreflang
+type Alternate struct {
+	Lang string `json:"lang"` // Language code, or x-default
+	URL  string `json:"url"`  // Canonical URL of the translation
+}
+
+// Link is an outgoing link from a page
+type Link struct {
+	URL      string   `json:"url"`      // Canonical target URL
+	Text     string   `json:"text"`     // Anchor text, or the alt text of a linked image
+	Rel      []string `json:"rel"`      // rel values such as nofollow, ugc and sponsored
+	Internal bool     `json:"internal"` // Target is on the same host as the page
+}
+
+// Image is an <img> on a page, with the alternatives its srcset and
+// <picture> offer
+type Image struct {
+	URL         string        `json:"url"`          // Absolute URL of src, or of the largest candidate if there is none
+	Sources     []ImageSource `json:"sources"`      // Candidates from srcset and <picture> <source> elements
+	Alt         string        `json:"alt"`          // Alt text
+	Title       string        `json:"title"`        // Title attribute
+	Width       int           `json:"width"`        // Width attribute, 0 if missing
+	Height      int           `json:"height"`       // Height attribute, 0 if missing
+	Caption     string        `json:"caption"`      // Text of the enclosing figure's figcaption
+	ContentType string        `json:"content_type"` // From a HEAD request, "" unless ImageHeaders is set
+	Size        int64         `json:"size"`         // Bytes, from a HEAD request; 0 if unknown
+}
+
+// ImageSource is one candidate of a srcset
+type ImageSource struct {
+	URL        string `json:"url"`        // Absolute image URL
+	Descriptor string `json:"descriptor"` // Width or density, such as 640w or 2x; "" if none
+	Media      string `json:"media"`      // Media query of the <source>, if any
+	Type       string `json:"type"`       // MIME type of the <source>, if any
 }
 
 type Meta struct {
@@ -18,4 +80,56 @@ type Meta struct {
 	Content string `json:"content"`
 }
 
+// StructuredData is what a page declares about itself for rich snippets.
+// JSON-LD wins over OpenGraph, which wins over Twitter Card tags
+type StructuredData struct {
+	Type        string       `json:"type"`        // schema.org @type or og:type, such as NewsArticle or article
+	Author      string       `json:"author"`      // Name of the first author
+	Published   time.Time    `json:"published"`   // Publish date, zero if unknown
+	Image       string       `json:"image"`       // Absolute URL of the main image
+	Breadcrumbs []Breadcrumb `json:"breadcrumbs"` // Trail from the site root to the page
+}
+
+// Breadcrumb is one step of a BreadcrumbList
+type Breadcrumb struct {
+	Name string `json:"name"` // Label shown for the step
+	URL  string `json:"url"`  // Absolute URL of the step, "" for the current page
+}
+
+// RobotsDirectives are the page-level rules from robots meta tags and the
+// X-Robots-Tag header. A noindex record carries only its URL and these
+type RobotsDirectives struct {
+	NoIndex   bool `json:"noindex"`   // Leave the page out of the index
+	NoFollow  bool `json:"nofollow"`  // Do not follow links from the page
+	NoArchive bool `json:"noarchive"` // Do not show a cached copy
+	NoSnippet bool `json:"nosnippet"` // Do not show a text snippet in results
+}
+
+// Validators are the response headers used to ask a server whether a page
+// changed since the last crawl
+type Validators struct {
+	ETag         string `json:"etag"`          // ETag header
+	LastModified string `json:"last_modified"` // Last-Modified header, as sent
+}
 
+// FeedItem is an article listed in an RSS or Atom feed
+type FeedItem struct {
+	URL       string    `json:"url"`       // Absolute link to the article
+	Title     string    `json:"title"`     // Item title
+	Published time.Time `json:"published"` // Publish date, or last update if that is all there is; zero if unknown
+}
+
+// Origin is the seed a URL was reached from, and how many links in a row
+// outside that seed's scope led to it
+type Origin struct {
+	Seed string `json:"seed"` // Canonical seed URL
+	Hops int    `json:"hops"` // Off-site links in a row, 0 for an on-site URL
+}
+
+// SitemapEntry is a URL listed in a sitemap with the hints that came with it
+type SitemapEntry struct {
+	URL        string    `json:"url"`        // Page URL
+	LastMod    time.Time `json:"lastmod"`    // When the page last changed, zero if unknown
+	ChangeFreq string    `json:"changefreq"` // always, hourly, daily, weekly, monthly, yearly or never
+	Priority   float64   `json:"priority"`   // 0.0 to 1.0, 0.5 if unset
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// bypassRule lets the crawler ignore robots.txt on a host, or on the
+// subdomains of a domain, optionally only under some path prefixes
+type bypassRule struct {
+	host     string
+	wildcard bool     // Match subdomains of host rather than host itself
+	paths    []string // Path prefixes; none means the whole host
+	entry    string   // As written in config.rcf, for the audit log
+}
+
+var bypassRules []bypassRule
+
+// parseBypass reads a Bypass: line of hosts, each optionally written as a
+// URL or as *.domain for its subdomains. Fields starting with / are path
+// prefixes that limit every host on the line
+func parseBypass(line string) error {
+	var hosts []bypassRule
+	var paths []string
+	for _, field := range strings.Fields(line) {
+		if strings.HasPrefix(field, "/") {
+			paths = append(paths, field)
+			continue
+		}
+
+		// Entries used to be written as URLs, like https://google.com
+		entry := field
+		if !strings.Contains(field, "://") {
+			field = "http://" + field
+		}
+		parsed, err := url.Parse(field)
+		if err != nil || parsed.Hostname() == "" {
+			return fmt.Errorf("invalid host %q in Bypass entry %q", entry, line)
+		}
+
+		rule := bypassRule{host: strings.ToLower(parsed.Hostname()), entry: entry}
+		if strings.HasPrefix(rule.host, "*.") {
+			rule.host, rule.wildcard = rule.host[2:], true
+		}
+		if strings.Contains(rule.host, "*") {
+			return fmt.Errorf("invalid wildcard %q in Bypass entry %q: only a leading *. is supported", entry, line)
+		}
+		if parsed.Path != "" && parsed.Path != "/" {
+			rule.paths = []string{parsed.Path}
+		}
+		hosts = append(hosts, rule)
+	}
+
+	for _, rule := range hosts {
+		rule.paths = append(rule.paths, paths...)
+		bypassRules = append(bypassRules, rule)
+	}
+	return nil
+}
+
+// bypassFor returns the Bypass: entry that exempts a URL from robots.txt
+func bypassFor(parsedURL *url.URL) (string, bool) {
+	host := strings.ToLower(parsedURL.Hostname())
+	for _, rule := range bypassRules {
+		if rule.wildcard {
+			if !strings.HasSuffix(host, "."+rule.host) {
+				continue
+			}
+		} else if host != rule.host {
+			continue
+		}
+
+		if len(rule.paths) == 0 {
+			return rule.entry, true
+		}
+		for _, prefix := range rule.paths {
+			if underPath(parsedURL.Path, prefix) {
+				return rule.entry, true
+			}
+		}
+	}
+	return "", false
+}
+
+// underPath reports whether a path lies under a prefix on a segment
+// boundary, so /maps covers /maps/x but not /mapsecret
+func underPath(path, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || strings.HasSuffix(prefix, "/") || path[len(prefix)] == '/'
+}
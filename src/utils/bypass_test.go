@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestBypassFor(t *testing.T) {
+	defer func(rules []bypassRule) { bypassRules = rules }(bypassRules)
+	bypassRules = nil
+	for _, line := range []string{"https://google.com/maps", "*.example.com /docs", "example.org /files/"} {
+		if err := parseBypass(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://google.com/maps", true},
+		{"https://google.com/maps/place/x", true},
+		{"https://google.com/mapsecret", false},
+		{"https://google.com/", false},
+		{"https://www.example.com/docs", true},
+		{"https://www.example.com/docs/guide", true},
+		{"https://www.example.com/docs-private/x", false},
+		{"https://example.com/docs", false},
+		{"https://example.org/files/a.pdf", true},
+		{"https://example.org/filesystem", false},
+	}
+	for _, test := range tests {
+		parsed, err := url.Parse(test.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, got := bypassFor(parsed); got != test.want {
+			t.Errorf("bypassFor(%q) = %v, want %v", test.url, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package utils
+
+import (
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// Query parameters that only track where a visitor came from. Entries
+// ending in * match any parameter with that prefix. More can be added
+// under StripParams: in config.rcf
+var stripParams = []string{
+	"utm_*", "gclid", "dclid", "fbclid", "msclkid", "yclid", "mc_cid", "mc_eid",
+	"_ga", "_gl", "igshid", "ref_src", "spm",
+}
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// Canonicalize normalizes a URL so that every spelling of the same page
+// dedups to one string. Returns "" if the URL cannot be parsed
+func Canonicalize(rawURL string) string {
+	parsedURL, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return ""
+	}
+
+	// mailto:, javascript: and friends have nothing to normalize
+	if parsedURL.Opaque != "" || parsedURL.Host == "" {
+		return parsedURL.String()
+	}
+
+	scheme := strings.ToLower(parsedURL.Scheme)
+	host, ok := canonicalHost(scheme, parsedURL.Hostname(), parsedURL.Port())
+	if !ok {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(scheme)
+	b.WriteString("://")
+	if parsedURL.User != nil {
+		b.WriteString(parsedURL.User.String())
+		b.WriteString("@")
+	}
+	b.WriteString(host)
+
+	path := removeDotSegments(normalizeEscapes(parsedURL.EscapedPath()))
+	if path == "" {
+		path = "/"
+	}
+	b.WriteString(path)
+
+	if query := canonicalQuery(parsedURL.RawQuery); query != "" {
+		b.WriteString("?")
+		b.WriteString(query)
+	}
+
+	// The fragment is dropped: it never reaches the server
+	return b.String()
+}
+
+// canonicalHost lowercases the host, converts IDNs to punycode and drops
+// the scheme's default port. The host comes without the brackets of an
+// IPv6 literal, which are put back here
+func canonicalHost(scheme, host, port string) (string, bool) {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if !strings.Contains(host, ":") { // IPv6 literals are left alone
+		ascii, err := idna.Lookup.ToASCII(host)
+		if err != nil {
+			return "", false
+		}
+		host = ascii
+	}
+
+	if port == defaultPorts[scheme] {
+		port = ""
+	}
+	if port != "" {
+		return net.JoinHostPort(host, port), true
+	}
+	if strings.Contains(host, ":") {
+		return "[" + host + "]", true
+	}
+	return host, true
+}
+
+// canonicalQuery drops tracking parameters and sorts the rest
+func canonicalQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	var params []string
+	for _, param := range strings.FieldsFunc(rawQuery, func(r rune) bool { return r == '&' || r == ';' }) {
+		param = normalizeEscapes(param)
+		name, _, _ := strings.Cut(param, "=")
+		if isTrackingParam(name) {
+			continue
+		}
+		params = append(params, param)
+	}
+
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+func isTrackingParam(name string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range stripParams {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeEscapes decodes percent-escaped unreserved characters and
+// uppercases the hex digits of every escape that has to stay
+func normalizeEscapes(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		c := unhex(s[i+1])<<4 | unhex(s[i+2])
+		if isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('%')
+			b.WriteString(strings.ToUpper(s[i+1 : i+3]))
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+// removeDotSegments resolves "." and ".." as described in RFC 3986 5.2.4
+func removeDotSegments(path string) string {
+	if !strings.Contains(path, ".") {
+		return path
+	}
+
+	segments := strings.Split(path, "/")
+	out := make([]string, 0, len(segments))
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		switch segment {
+		case ".":
+			if last {
+				out = append(out, "")
+			}
+		case "..":
+			if len(out) > 1 {
+				out = out[:len(out)-1]
+			}
+			if last {
+				out = append(out, "")
+			}
+		default:
+			out = append(out, segment)
+		}
+	}
+	return strings.Join(out, "/")
+}
+
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
@@ -0,0 +1,41 @@
+package utils
+
+import "testing"
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"lowercases scheme and host", "HTTP://Example.COM/Path", "http://example.com/Path"},
+		{"adds root path", "http://example.com", "http://example.com/"},
+		{"drops fragment", "http://example.com/a#top", "http://example.com/a"},
+		{"drops trailing dot of host", "http://example.com./a", "http://example.com/a"},
+		{"drops default http port", "http://example.com:80/a", "http://example.com/a"},
+		{"drops default https port", "https://example.com:443/a", "https://example.com/a"},
+		{"keeps other ports", "http://example.com:8080/a", "http://example.com:8080/a"},
+		{"keeps https port on http", "http://example.com:443/a", "http://example.com:443/a"},
+		{"ipv6 without port", "http://[::1]/x", "http://[::1]/x"},
+		{"ipv6 with default port", "http://[::1]:80/x", "http://[::1]/x"},
+		{"ipv6 with port", "http://[2001:DB8::1]:8080/x", "http://[2001:db8::1]:8080/x"},
+		{"idn to punycode", "http://Bücher.example/", "http://xn--bcher-kva.example/"},
+		{"punycode kept", "http://xn--bcher-kva.example/", "http://xn--bcher-kva.example/"},
+		{"dot segments", "http://example.com/a/./b/../c", "http://example.com/a/c"},
+		{"dot segments past root", "http://example.com/../../a", "http://example.com/a"},
+		{"trailing dot dot", "http://example.com/a/b/..", "http://example.com/a/"},
+		{"decodes unreserved escapes", "http://example.com/%7Euser/%41", "http://example.com/~user/A"},
+		{"uppercases kept escapes", "http://example.com/a%2fb%3a", "http://example.com/a%2Fb%3A"},
+		{"sorts query", "http://example.com/?b=2&a=1", "http://example.com/?a=1&b=2"},
+		{"strips tracking params", "http://example.com/?utm_source=x&id=3&fbclid=y&UTM_Medium=z", "http://example.com/?id=3"},
+		{"drops empty query", "http://example.com/?gclid=1", "http://example.com/"},
+		{"keeps user info", "http://user@example.com/", "http://user@example.com/"},
+		{"opaque left alone", "mailto:a@example.com", "mailto:a@example.com"},
+		{"unparsable", "http://[::1/x", ""},
+	}
+	for _, test := range tests {
+		if got := Canonicalize(test.in); got != test.want {
+			t.Errorf("%s: Canonicalize(%q) = %q, want %q", test.name, test.in, got, test.want)
+		}
+	}
+}
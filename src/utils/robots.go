@@ -0,0 +1,175 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/temoto/robotstxt"
+)
+
+const (
+	robotsTimeout  = 10 * time.Second // Same budget as a page fetch
+	robotsTTL      = 24 * time.Hour   // RFC 9309 caps caching at 24 hours
+	robotsErrorTTL = 10 * time.Minute // Unreachable hosts are retried sooner
+	robotsMaxBytes = 500 * 1024       // RFC 9309 minimum parse limit
+	robotsMaxHops  = 5                // RFC 9309 redirect limit
+)
+
+// robotsEntry caches the rules for one origin, including failed fetches
+type robotsEntry struct {
+	data    *robotstxt.RobotsData
+	expires time.Time
+	failed  bool          // robots.txt was unreachable or answered 5xx
+	ready   chan struct{} // Closed once the fetch has finished
+}
+
+var (
+	robotsMap = make(map[string]*robotsEntry)
+	robotsMu  sync.Mutex
+
+	robotsClient = &http.Client{
+		Timeout: robotsTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= robotsMaxHops {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		},
+	}
+)
+
+// CanCrawl reports whether robots.txt lets userAgent fetch targetURL. URLs
+// a Bypass: entry covers skip robots.txt, which is logged each time
+func CanCrawl(targetURL, userAgent string) bool {
+	parsedURL, err := url.Parse(targetURL)
+	if err != nil {
+		return false
+	}
+	if entry, ok := bypassFor(parsedURL); ok {
+		fmt.Printf("\r[Robots Bypass] %s (Bypass: %s)\n", targetURL, entry)
+		return true
+	}
+
+	robots := getRobots(parsedURL, userAgent)
+	return robots.TestAgent(parsedURL.RequestURI(), userAgent)
+}
+
+// CrawlDelay returns the Crawl-delay robots.txt asks of userAgent on the
+// host of targetURL, or zero if it sets none
+func CrawlDelay(targetURL, userAgent string) time.Duration {
+	parsedURL, err := url.Parse(targetURL)
+	if err != nil {
+		return 0
+	}
+
+	group := getRobots(parsedURL, userAgent).FindGroup(userAgent)
+	if group == nil {
+		return 0
+	}
+	return group.CrawlDelay
+}
+
+// RobotsRetryAfter returns how long until robots.txt for the host of
+// targetURL is fetched again if the last fetch failed, or zero if it did
+// not. RFC 9309 treats such a host as fully disallowed only for the time
+// being, so its URLs should wait rather than be dropped
+func RobotsRetryAfter(targetURL string) time.Duration {
+	parsedURL, err := url.Parse(targetURL)
+	if err != nil {
+		return 0
+	}
+	origin := parsedURL.Scheme + "://" + strings.ToLower(parsedURL.Host)
+
+	robotsMu.Lock()
+	defer robotsMu.Unlock()
+	entry, ok := robotsMap[origin]
+	if !ok || !entry.failed {
+		return 0
+	}
+	return max(time.Until(entry.expires), time.Second)
+}
+
+// getRobots returns the cached rules for the URL's origin, fetching them if
+// missing or expired. Concurrent callers share a single fetch
+func getRobots(parsedURL *url.URL, userAgent string) *robotstxt.RobotsData {
+	origin := parsedURL.Scheme + "://" + strings.ToLower(parsedURL.Host)
+
+	robotsMu.Lock()
+	entry, exists := robotsMap[origin]
+	if exists && (entry.expires.IsZero() || time.Now().Before(entry.expires)) {
+		robotsMu.Unlock()
+		<-entry.ready
+		return entry.data
+	}
+	entry = &robotsEntry{ready: make(chan struct{})}
+	robotsMap[origin] = entry
+	robotsMu.Unlock()
+
+	robots, ttl, failed := fetchRobotsTxt(origin, userAgent)
+
+	robotsMu.Lock()
+	entry.data = robots
+	entry.expires = time.Now().Add(ttl)
+	entry.failed = failed
+	robotsMu.Unlock()
+	close(entry.ready)
+
+	return robots
+}
+
+// fetchRobotsTxt downloads and parses robots.txt following RFC 9309:
+// 2xx is parsed, 4xx allows everything, 5xx or an unreachable host
+// disallows everything until the shorter error TTL runs out. Returns the
+// rules, how long to cache them, and whether the fetch failed
+func fetchRobotsTxt(origin, userAgent string) (*robotstxt.RobotsData, time.Duration, bool) {
+	unreachable, _ := robotstxt.FromStatusAndBytes(http.StatusServiceUnavailable, nil)
+
+	req, err := http.NewRequest(http.MethodGet, origin+"/robots.txt", nil)
+	if err != nil {
+		return unreachable, robotsErrorTTL, true
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := robotsClient.Do(req)
+	if err != nil {
+		return unreachable, robotsErrorTTL, true
+	}
+	defer resp.Body.Close()
+
+	// Too many requests is a server telling us to back off, not a missing file
+	status := resp.StatusCode
+	if status == http.StatusTooManyRequests {
+		status = http.StatusServiceUnavailable
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, robotsMaxBytes))
+	if err != nil {
+		return unreachable, robotsErrorTTL, true
+	}
+
+	robots, err := robotstxt.FromStatusAndBytes(status, body)
+	if err != nil {
+		// Unparseable or an unexpected status (e.g. a redirect loop): treat
+		// it as a missing file, which RFC 9309 says allows everything
+		robots, _ = robotstxt.FromStatusAndBytes(http.StatusNotFound, nil)
+	}
+	if status >= 500 {
+		return robots, robotsErrorTTL, true
+	}
+	return robots, robotsTTL, false
+}
+
+// Sitemaps returns the Sitemap: URLs listed in the robots.txt for the
+// host of targetURL
+func Sitemaps(targetURL, userAgent string) []string {
+	parsedURL, err := url.Parse(targetURL)
+	if err != nil {
+		return nil
+	}
+	return getRobots(parsedURL, userAgent).Sitemaps
+}
@@ -3,15 +3,17 @@ package utils
 import (
 	"bufio"
 	"fmt"
-	"io"
-	"net/http"
+	"net"
 	"net/url"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/temoto/robotstxt"
+	"webcrawler/config"
+	"webcrawler/filter"
 )
 
 // Progress tracking
@@ -30,10 +32,9 @@ func UpdateProgress(inQueue, processed int64) {
 }
 
 var (
-	robotsMap  = make(map[string]*robotstxt.RobotsData)
-	robotsMu   sync.Mutex
-	blacklist  = make(map[string]bool)
-	bypassList = make(map[string]bool)
+	urlFilter  filter.Filter
+	hostLimits = make(map[string]config.HostLimit)
+	seedScopes = make(map[string]config.Scope)
 )
 
 func ReadConfig(filename string) ([]string, error) {
@@ -44,6 +45,7 @@ func ReadConfig(filename string) ([]string, error) {
 	defer file.Close()
 
 	var urls []string
+	scopes := make(map[string]config.Scope) // Keyed by seed as written
 	scanner := bufio.NewScanner(file)
 	section := ""
 	for scanner.Scan() {
@@ -57,77 +59,166 @@ func ReadConfig(filename string) ([]string, error) {
 		}
 		switch section {
 		case "Websites":
-			urls = append(urls, strings.Fields(line)...)
+			seeds, err := parseWebsites(line, scopes)
+			if err != nil {
+				return nil, err
+			}
+			urls = append(urls, seeds...)
 		case "Blacklist":
 			for _, item := range strings.Fields(line) {
-				blacklist[item] = true
+				rule, err := filter.HostRule("blacklist:"+item, item, false)
+				if err != nil {
+					return nil, fmt.Errorf("invalid Blacklist entry: %w", err)
+				}
+				urlFilter.Add(rule)
+			}
+		case "BlacklistFiles":
+			for _, path := range strings.Fields(line) {
+				if err := loadBlacklistFile(filename, path); err != nil {
+					return nil, err
+				}
 			}
+		case "Allow", "Deny":
+			rule, err := filter.ParseRule(line, section == "Allow")
+			if err != nil {
+				return nil, err
+			}
+			urlFilter.Add(rule)
 		case "Bypass":
-			for _, item := range strings.Fields(line) {
-				bypassList[item] = true
+			if err := parseBypass(line); err != nil {
+				return nil, err
+			}
+		case "StripParams":
+			stripParams = append(stripParams, strings.Fields(line)...)
+		case "HostLimits":
+			if err := parseHostLimit(line); err != nil {
+				return nil, err
 			}
 		}
 	}
-	return urls, scanner.Err()
-}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
 
-func IsBlacklisted(targetURL string) bool {
-	for pattern := range blacklist {
-		if strings.Contains(targetURL, pattern) {
-			return true
-		}
+	// Seeds are canonicalized once StripParams, wherever it sits, is known
+	for seed, scope := range scopes {
+		seedScopes[Canonicalize(seed)] = scope
 	}
-	return false
+	return urls, nil
 }
 
-func CanCrawl(targetURL, userAgent string) bool {
-	if strings.Contains(targetURL, "://"+domainForBypass()) {
-		return true
+// loadBlacklistFile adds the domains of a blocklist to the rules as one
+// rule named after the file. A relative path is taken from the directory
+// of the config file
+func loadBlacklistFile(configFile, path string) error {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(configFile), path)
 	}
-
-	parsedURL, err := url.Parse(targetURL)
+	trie := &filter.HostTrie{}
+	count, err := trie.LoadBlocklist(path)
 	if err != nil {
-		return false
+		return fmt.Errorf("loading blacklist file: %w", err)
 	}
-	domain := parsedURL.Host
+	urlFilter.Add(filter.MatcherRule("blacklist-file:"+filepath.Base(path), false, trie.Match))
+	fmt.Printf("Loaded %d blacklisted domains from %s\n", count, path)
+	return nil
+}
 
-	robotsMu.Lock()
-	robots, exists := robotsMap[domain]
-	robotsMu.Unlock()
+// parseHostLimit reads a "domain requests/sec max-in-flight" line
+func parseHostLimit(line string) error {
+	fields := strings.Fields(line)
+	if len(fields) != 3 {
+		return fmt.Errorf("invalid HostLimits entry %q: want \"domain rate inflight\"", line)
+	}
+	rateLimit, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil || rateLimit <= 0 {
+		return fmt.Errorf("invalid rate in HostLimits entry %q", line)
+	}
+	maxInFlight, err := strconv.Atoi(fields[2])
+	if err != nil || maxInFlight < 1 {
+		return fmt.Errorf("invalid in-flight count in HostLimits entry %q", line)
+	}
+	hostLimits[strings.ToLower(fields[0])] = config.HostLimit{RateLimit: rateLimit, MaxInFlight: maxInFlight}
+	return nil
+}
+
+// parseWebsites reads a line of seed URLs. Options among them, such as
+// "scope=domain allow=cdn.example.com hops=1", set the scope of every seed
+// on the line, which is added to scopes
+func parseWebsites(line string, scopes map[string]config.Scope) ([]string, error) {
+	var seeds []string
+	scope := config.Scope{MaxOffsiteHops: -1}
+	scoped := false
+	for _, field := range strings.Fields(line) {
+		// A seed's query may hold = too, but an option's key is a bare word
+		key, value, isOption := strings.Cut(field, "=")
+		if !isOption || strings.ContainsAny(key, ":/?.") {
+			seeds = append(seeds, field)
+			continue
+		}
 
-	if !exists {
-		robots = fetchRobotsTxt(parsedURL, domain)
+		scoped = true
+		switch key {
+		case "scope":
+			if value != "host" && value != "domain" && value != "any" {
+				return nil, fmt.Errorf("invalid scope in Websites entry %q: want host, domain or any", line)
+			}
+			scope.Mode = value
+		case "allow":
+			for _, domain := range strings.Split(value, ",") {
+				if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+					scope.Allow = append(scope.Allow, domain)
+				}
+			}
+		case "hops":
+			hops, err := strconv.Atoi(value)
+			if err != nil || hops < 0 {
+				return nil, fmt.Errorf("invalid hops in Websites entry %q", line)
+			}
+			scope.MaxOffsiteHops = hops
+		default:
+			return nil, fmt.Errorf("unknown option %q in Websites entry %q", key, line)
+		}
 	}
 
-	if robots != nil {
-		return robots.TestAgent(parsedURL.Path, userAgent)
+	if scoped {
+		for _, seed := range seeds {
+			scopes[seed] = scope
+		}
 	}
-	return true
+	return seeds, nil
 }
 
-func domainForBypass() string {
-	var domain string
-	for domain = range bypassList {
-		return domain
-	}
-	return ""
+// SeedScope returns the scope config.rcf sets for a seed, if any
+func SeedScope(seed string) (config.Scope, bool) {
+	scope, ok := seedScopes[seed]
+	return scope, ok
 }
 
-func fetchRobotsTxt(parsedURL *url.URL, domain string) *robotstxt.RobotsData {
-	robotsURL := parsedURL.Scheme + "://" + domain + "/robots.txt"
-	resp, err := http.Get(robotsURL)
-	if err == nil && resp.StatusCode == http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		robots, err := robotstxt.FromBytes(body)
-		if err == nil {
-			robotsMu.Lock()
-			robotsMap[domain] = robots
-			robotsMu.Unlock()
-			return robots
+// HostLimitFor returns the override for a host or its closest parent domain
+func HostLimitFor(host string) (config.HostLimit, bool) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	for host != "" {
+		if limit, ok := hostLimits[host]; ok {
+			return limit, true
+		}
+		dot := strings.Index(host, ".")
+		if dot < 0 {
+			break
 		}
+		host = host[dot+1:]
 	}
-	return nil
+	return config.HostLimit{}, false
+}
+
+// CheckURL runs a URL through the Allow:, Deny: and Blacklist: rules in the
+// order config.rcf lists them. Returns whether it may be crawled and the
+// name of the rule that decided, "" if none did
+func CheckURL(targetURL string) (bool, string) {
+	return urlFilter.Check(targetURL)
 }
 
 func ResolveURL(base, link string) string {